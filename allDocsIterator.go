@@ -0,0 +1,116 @@
+package couchdb
+
+import "context"
+
+// DefaultPageSize is the number of rows fetched per request by iterators
+// when no page size is given.
+const DefaultPageSize = 100
+
+// AllDocsIterator iterates over the rows of the _all_docs URL.
+// Rows are requested page by page using startkey and startkey_docid
+// so that no page is skipped or read twice.
+//
+//	it := db.IterAllDocs(ctx, nil, 100)
+//	for it.Next() {
+//	  row := it.Row()
+//	}
+//	if err := it.Err(); err != nil {
+//	  // handle error
+//	}
+type AllDocsIterator struct {
+	ctx      context.Context
+	db       *Database
+	params   QueryParameters
+	pageSize int
+	// remaining is the number of rows left if params.Limit was set, -1 otherwise
+	remaining int
+	rows      []Row
+	row       Row
+	last      bool
+	err       error
+}
+
+func newAllDocsIterator(ctx context.Context, db *Database, params *QueryParameters, pageSize int) *AllDocsIterator {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	it := &AllDocsIterator{
+		ctx:       ctx,
+		db:        db,
+		pageSize:  pageSize,
+		remaining: -1,
+	}
+	if params != nil {
+		it.params, it.err = pagingParams(*params)
+		if params.Limit != nil {
+			it.remaining = *params.Limit
+		}
+	}
+	return it
+}
+
+// Next advances the iterator to the next row. It returns false when there are
+// no more rows, an error occurred or the context was canceled.
+func (it *AllDocsIterator) Next() bool {
+	if it.err != nil || it.remaining == 0 {
+		return false
+	}
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+	if len(it.rows) == 0 {
+		if it.last {
+			return false
+		}
+		if err := it.fetch(); err != nil {
+			it.err = err
+			return false
+		}
+		if len(it.rows) == 0 {
+			return false
+		}
+	}
+	it.row, it.rows = it.rows[0], it.rows[1:]
+	if it.remaining > 0 {
+		it.remaining--
+	}
+	return true
+}
+
+// Row returns the current row.
+func (it *AllDocsIterator) Row() Row {
+	return it.row
+}
+
+// Err returns the first error that occurred during iteration.
+func (it *AllDocsIterator) Err() error {
+	return it.err
+}
+
+// fetch requests the next page. It asks for one extra row
+// whose key and id are the start of the following page.
+func (it *AllDocsIterator) fetch() error {
+	limit := it.pageSize + 1
+	it.params.Limit = &limit
+	res, err := it.db.allDocs(it.ctx, &it.params)
+	if err != nil {
+		return err
+	}
+	if len(res.Rows) <= it.pageSize {
+		it.rows = res.Rows
+		it.last = true
+		return nil
+	}
+	next := res.Rows[it.pageSize]
+	startKeyDocID := next.ID
+	it.params.StartKey = next.Key
+	it.params.StartKeyDocID = &startKeyDocID
+	// skip only applies to the first page
+	it.params.Skip = nil
+	it.rows = res.Rows[:it.pageSize]
+	return nil
+}
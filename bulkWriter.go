@@ -0,0 +1,234 @@
+package couchdb
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBulkWriterClosed is returned when adding documents to a closed BulkWriter.
+var ErrBulkWriterClosed = errors.New("couchdb: bulk writer is closed")
+
+// Default values for BulkWriterOptions.
+const (
+	DefaultBulkBatchSize     = 500
+	DefaultBulkFlushInterval = time.Second
+	DefaultBulkWorkers       = 1
+)
+
+// BulkWriterOptions configures a BulkWriter.
+// Zero values fall back to the defaults above.
+type BulkWriterOptions struct {
+	// BatchSize is the maximum number of documents per _bulk_docs request.
+	BatchSize int
+	// BatchBytes is the maximum JSON size of all documents in a batch.
+	// A value of zero disables the size limit.
+	BatchBytes int
+	// FlushInterval is the maximum time a document waits in a batch.
+	FlushInterval time.Duration
+	// Workers is the number of concurrent _bulk_docs requests.
+	Workers int
+	// QueueSize is the number of documents that can be buffered before Add blocks.
+	// It defaults to BatchSize times Workers.
+	QueueSize int
+	// Results receives one BulkResult per document. It is never closed by the BulkWriter
+	// and must be drained by the caller, otherwise the workers block.
+	Results chan<- BulkResult
+	// OnResult is called from the worker goroutines with one BulkResult per document.
+	OnResult func(BulkResult)
+}
+
+// BulkResult is the outcome of writing a single document with a BulkWriter.
+// Err is set when the whole request failed, Response.Error when CouchDB
+// rejected only this document, e.g. because of a conflict.
+type BulkResult struct {
+	Doc      CouchDoc
+	Response DocumentResponse
+	Err      error
+}
+
+// BulkWriter collects documents from many goroutines and writes them
+// in batches via the _bulk_docs endpoint.
+// http://docs.couchdb.org/en/latest/api/database/bulk-api.html#post--db-_bulk_docs
+type BulkWriter struct {
+	db      DatabaseService
+	options BulkWriterOptions
+
+	mu     sync.RWMutex
+	closed bool
+
+	queue   chan bulkItem
+	batches chan []bulkItem
+	// inflight is only incremented by the batcher goroutine
+	inflight sync.WaitGroup
+	workers  sync.WaitGroup
+	done     chan struct{}
+}
+
+// bulkItem is either a document or a flush marker.
+type bulkItem struct {
+	doc   CouchDoc
+	size  int
+	flush chan struct{}
+}
+
+// NewBulkWriter returns a new BulkWriter for the given database and starts its workers.
+// Call Close to write all pending documents and release the workers.
+func NewBulkWriter(db DatabaseService, options BulkWriterOptions) *BulkWriter {
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultBulkBatchSize
+	}
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = DefaultBulkFlushInterval
+	}
+	if options.Workers <= 0 {
+		options.Workers = DefaultBulkWorkers
+	}
+	if options.QueueSize <= 0 {
+		options.QueueSize = options.BatchSize * options.Workers
+	}
+	w := &BulkWriter{
+		db:      db,
+		options: options,
+		queue:   make(chan bulkItem, options.QueueSize),
+		batches: make(chan []bulkItem),
+		done:    make(chan struct{}),
+	}
+	for i := 0; i < options.Workers; i++ {
+		w.workers.Add(1)
+		go w.work()
+	}
+	go w.batch()
+	return w
+}
+
+// Add queues a document for writing. It blocks when the queue is full.
+func (w *BulkWriter) Add(doc CouchDoc) error {
+	item := bulkItem{doc: doc}
+	if w.options.BatchBytes > 0 {
+		b, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		item.size = len(b)
+	}
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return ErrBulkWriterClosed
+	}
+	w.queue <- item
+	return nil
+}
+
+// Flush writes all documents added so far and blocks until
+// their results have been delivered.
+func (w *BulkWriter) Flush() error {
+	flush := make(chan struct{})
+	w.mu.RLock()
+	if w.closed {
+		w.mu.RUnlock()
+		return ErrBulkWriterClosed
+	}
+	w.queue <- bulkItem{flush: flush}
+	w.mu.RUnlock()
+	<-flush
+	return nil
+}
+
+// Close writes all pending documents, waits for the workers to finish
+// and rejects any further documents.
+func (w *BulkWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrBulkWriterClosed
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+	<-w.done
+	return nil
+}
+
+// batch groups queued documents and hands them to the workers.
+func (w *BulkWriter) batch() {
+	ticker := time.NewTicker(w.options.FlushInterval)
+	defer ticker.Stop()
+	var (
+		batch []bulkItem
+		size  int
+	)
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		w.inflight.Add(1)
+		w.batches <- batch
+		batch = nil
+		size = 0
+	}
+	for {
+		select {
+		case item, ok := <-w.queue:
+			if !ok {
+				send()
+				close(w.batches)
+				w.workers.Wait()
+				close(w.done)
+				return
+			}
+			if item.flush != nil {
+				send()
+				w.inflight.Wait()
+				close(item.flush)
+				continue
+			}
+			// start a new batch if this document would exceed the size limit
+			if w.options.BatchBytes > 0 && size+item.size > w.options.BatchBytes {
+				send()
+			}
+			batch = append(batch, item)
+			size += item.size
+			if len(batch) >= w.options.BatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		}
+	}
+}
+
+// work writes batches and delivers the results.
+func (w *BulkWriter) work() {
+	defer w.workers.Done()
+	for batch := range w.batches {
+		docs := make([]CouchDoc, len(batch))
+		for i, item := range batch {
+			docs[i] = item.doc
+		}
+		responses, err := w.db.Bulk(docs)
+		for i, doc := range docs {
+			result := BulkResult{
+				Doc: doc,
+				Err: err,
+			}
+			// CouchDB returns one response per document in the same order
+			if err == nil && i < len(responses) {
+				result.Response = responses[i]
+			}
+			w.deliver(result)
+		}
+		w.inflight.Done()
+	}
+}
+
+func (w *BulkWriter) deliver(result BulkResult) {
+	if w.options.OnResult != nil {
+		w.options.OnResult(result)
+	}
+	if w.options.Results != nil {
+		w.options.Results <- result
+	}
+}
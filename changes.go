@@ -0,0 +1,215 @@
+package couchdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/google/go-querystring/query"
+)
+
+// Values for ChangesParameters.Feed.
+const (
+	FeedNormal      = "normal"
+	FeedLongpoll    = "longpoll"
+	FeedContinuous  = "continuous"
+	FeedEventSource = "eventsource"
+)
+
+// StyleAllDocs returns all leaf revisions instead of only the winning one.
+const StyleAllDocs = "all_docs"
+
+// Built-in values for ChangesParameters.Filter.
+// Any other value is the name of a filter function in a design document,
+// e.g. "animals/byOwner".
+const (
+	FilterDocIDs   = "_doc_ids"
+	FilterSelector = "_selector"
+	FilterView     = "_view"
+	FilterDesign   = "_design"
+)
+
+// Seq is an opaque update sequence.
+// CouchDB 1.x uses integers whereas CouchDB 2.x and later use strings.
+// Seq remembers which form it was decoded from and is encoded in the same form.
+// The zero value is an empty sequence which is encoded as null.
+type Seq struct {
+	value string
+	// raw is set for sequences that were JSON numbers or arrays,
+	// value then holds their JSON.
+	raw bool
+}
+
+// NewSeq returns a sequence from a string, e.g. NewSeq("now") for ChangesParameters.Since.
+// It is encoded as JSON string.
+func NewSeq(value string) Seq {
+	return Seq{value: value}
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+//
+// https://golang.org/pkg/encoding/json/#Unmarshaler
+func (s *Seq) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*s = Seq{}
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var tmp string
+		if err := json.Unmarshal(data, &tmp); err != nil {
+			return err
+		}
+		*s = Seq{value: tmp}
+		return nil
+	}
+	// integer sequences (and arrays used by some clustered versions) are kept as raw JSON
+	*s = Seq{value: string(data), raw: true}
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+//
+// https://golang.org/pkg/encoding/json/#Marshaler
+func (s Seq) MarshalJSON() ([]byte, error) {
+	if s.IsZero() {
+		return []byte("null"), nil
+	}
+	if s.raw {
+		return []byte(s.value), nil
+	}
+	return json.Marshal(s.value)
+}
+
+// EncodeValues implements the query.Encoder interface so that Seq can be used
+// as url query parameter. Empty sequences are omitted.
+//
+// https://godoc.org/github.com/google/go-querystring/query#Encoder
+func (s Seq) EncodeValues(key string, v *url.Values) error {
+	if !s.IsZero() {
+		v.Set(key, s.value)
+	}
+	return nil
+}
+
+// IsZero reports whether the sequence is empty.
+func (s Seq) IsZero() bool {
+	return s.value == ""
+}
+
+// String returns the sequence as used in the since query parameter.
+func (s Seq) String() string {
+	return s.value
+}
+
+// ChangesParameters is struct to define url query parameters for the _changes URL.
+// DocIDs and Selector are sent in the body of a POST request and imply
+// the _doc_ids and _selector filters. View implies the _view filter.
+// Query holds additional parameters for filter functions in design documents.
+// http://docs.couchdb.org/en/latest/api/database/changes.html
+type ChangesParameters struct {
+	Feed            string            `url:"feed,omitempty"`
+	Since           Seq               `url:"since,omitempty"`
+	Style           string            `url:"style,omitempty"`
+	Filter          string            `url:"filter,omitempty"`
+	View            string            `url:"view,omitempty"`
+	DocIDs          []string          `url:"-"`
+	Selector        interface{}       `url:"-"`
+	Query           map[string]string `url:"-"`
+	Conflicts       *bool             `url:"conflicts,omitempty"`
+	Descending      *bool             `url:"descending,omitempty"`
+	IncludeDocs     *bool             `url:"include_docs,omitempty"`
+	Attachments     *bool             `url:"attachments,omitempty"`
+	AttEncodingInfo *bool             `url:"att_encoding_info,omitempty"`
+	Limit           *int              `url:"limit,omitempty"`
+	SeqInterval     *int              `url:"seq_interval,omitempty"`
+	Timeout         *int              `url:"timeout,omitempty"`
+	Heartbeat       *int              `url:"heartbeat,omitempty"`
+}
+
+// ChangesResponse is response from GET request to the _changes URL.
+type ChangesResponse struct {
+	Results []Change `json:"results"`
+	LastSeq Seq      `json:"last_seq"`
+	Pending int      `json:"pending"`
+}
+
+// Change is a single row inside the changes feed.
+// Seq may be empty when seq_interval is used.
+type Change struct {
+	Seq     Seq                    `json:"seq"`
+	ID      string                 `json:"id"`
+	Changes []ChangeRev            `json:"changes"`
+	Deleted bool                   `json:"deleted,omitempty"`
+	Doc     map[string]interface{} `json:"doc,omitempty"`
+}
+
+// ChangeRev is a changed revision of a document inside the changes feed.
+type ChangeRev struct {
+	Rev string `json:"rev"`
+}
+
+// Changes returns a sorted list of changes made to documents in the database.
+// Only the normal and longpoll feeds are supported.
+//
+// http://docs.couchdb.org/en/latest/api/database/changes.html
+func (db *Database) Changes(params *ChangesParameters) (*ChangesResponse, error) {
+	if params != nil && params.Feed != "" && params.Feed != FeedNormal && params.Feed != FeedLongpoll {
+		return nil, fmt.Errorf("couchdb: feed %q is not supported by Changes", params.Feed)
+	}
+	res, err := db.changesRequest(context.Background(), params, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var response ChangesResponse
+	return &response, json.NewDecoder(res.Body).Decode(&response)
+}
+
+// changesRequest requests the _changes URL and returns the response with an open body.
+// Filters that need a request body are sent as POST request.
+func (db *Database) changesRequest(ctx context.Context, params *ChangesParameters, header http.Header) (*http.Response, error) {
+	q, err := query.Values(params)
+	if err != nil {
+		return nil, err
+	}
+	if params == nil {
+		params = &ChangesParameters{}
+	}
+	for key, value := range params.Query {
+		q.Set(key, value)
+	}
+	var body interface{}
+	switch {
+	case params.DocIDs != nil && params.Selector != nil:
+		return nil, errors.New("couchdb: doc ids and selector cannot be used together")
+	case params.DocIDs != nil:
+		q.Set("filter", FilterDocIDs)
+		body = struct {
+			DocIDs []string `json:"doc_ids"`
+		}{params.DocIDs}
+	case params.Selector != nil:
+		q.Set("filter", FilterSelector)
+		body = struct {
+			Selector interface{} `json:"selector"`
+		}{params.Selector}
+	case params.View != "" && params.Filter == "":
+		q.Set("filter", FilterView)
+	}
+	u := fmt.Sprintf("%s/_changes?%s", url.PathEscape(db.Name), q.Encode())
+	if header == nil {
+		header = http.Header{}
+	}
+	if body == nil {
+		return db.Client.requestHeader(ctx, http.MethodGet, u, nil, header)
+	}
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(body); err != nil {
+		return nil, err
+	}
+	header.Set("Content-Type", "application/json")
+	return db.Client.requestHeader(ctx, http.MethodPost, u, &b, header)
+}
@@ -0,0 +1,264 @@
+package couchdb
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Default values for ConsumerOptions.
+const (
+	DefaultCheckpointInterval = time.Second
+	DefaultRetryBackoff       = time.Second
+)
+
+// ConsumerOptions configures a ChangesConsumer.
+// Zero values fall back to the defaults above.
+type ConsumerOptions struct {
+	// Params are the parameters for the changes feed, e.g. filters or include_docs.
+	// Since is only used when no checkpoint exists yet.
+	Params *ChangesParameters
+	// Stream configures the underlying ChangesStream.
+	Stream StreamOptions
+	// Workers is the number of changes handled concurrently.
+	Workers int
+	// MaxRetries is the number of times a failed handler is retried.
+	MaxRetries int
+	// RetryBackoff is the wait time before the first retry.
+	// It doubles with every retry.
+	RetryBackoff time.Duration
+	// DeadLetter is called with changes whose handler failed after all retries.
+	// Without DeadLetter the consumer stops with the handler error.
+	DeadLetter func(Change, error)
+	// CheckpointInterval is the time between two checkpoint writes.
+	CheckpointInterval time.Duration
+}
+
+// ChangesConsumer handles every change of a database at least once.
+// Its progress is stored in the _local/<name> document so that a restarted
+// consumer resumes where the last one left off. With multiple workers the
+// checkpoint only moves past changes whose predecessors have all been handled.
+type ChangesConsumer struct {
+	db      DatabaseService
+	name    string
+	handler func(Change) error
+	options ConsumerOptions
+}
+
+// checkpoint is the _local document storing the progress of a consumer.
+type checkpoint struct {
+	Document
+	Seq Seq `json:"seq"`
+}
+
+// NewChangesConsumer returns a new consumer with the given name.
+// Consumers with the same name share their checkpoint.
+func NewChangesConsumer(db DatabaseService, name string, handler func(Change) error, options ConsumerOptions) *ChangesConsumer {
+	if options.Workers <= 0 {
+		options.Workers = 1
+	}
+	if options.RetryBackoff <= 0 {
+		options.RetryBackoff = DefaultRetryBackoff
+	}
+	if options.CheckpointInterval <= 0 {
+		options.CheckpointInterval = DefaultCheckpointInterval
+	}
+	return &ChangesConsumer{
+		db:      db,
+		name:    name,
+		handler: handler,
+		options: options,
+	}
+}
+
+// Checkpoint returns the sequence the consumer resumes from.
+// It is empty if the consumer never stored a checkpoint.
+func (c *ChangesConsumer) Checkpoint() (Seq, error) {
+	cp, err := c.loadCheckpoint()
+	if err != nil {
+		return Seq{}, err
+	}
+	return cp.Seq, nil
+}
+
+// Run consumes the changes feed until the context is canceled,
+// the feed ends or a handler fails without DeadLetter being set.
+// The checkpoint is written one last time before Run returns.
+// Canceling the context is not an error.
+func (c *ChangesConsumer) Run(ctx context.Context) error {
+	cp, err := c.loadCheckpoint()
+	if err != nil {
+		return err
+	}
+	params := ChangesParameters{}
+	if c.options.Params != nil {
+		params = *c.options.Params
+	}
+	if !cp.Seq.IsZero() {
+		params.Since = cp.Seq
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream := c.db.StreamChanges(ctx, &params, c.options.Stream)
+	defer stream.Close()
+
+	tracker := &seqTracker{}
+	jobs := make(chan *seqEntry)
+	var (
+		wg         sync.WaitGroup
+		once       sync.Once
+		handlerErr error
+	)
+	for i := 0; i < c.options.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for entry := range jobs {
+				if err := c.handle(ctx, entry.change); err != nil {
+					if ctx.Err() == nil {
+						once.Do(func() {
+							handlerErr = err
+							cancel()
+						})
+					}
+					// never move the checkpoint past an unhandled change
+					continue
+				}
+				tracker.complete(entry)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(c.options.CheckpointInterval)
+	defer ticker.Stop()
+	var runErr error
+loop:
+	for {
+		select {
+		case change, ok := <-stream.Changes():
+			if !ok {
+				runErr = stream.Err()
+				break loop
+			}
+			entry := tracker.add(change)
+			select {
+			case jobs <- entry:
+			case <-ctx.Done():
+				break loop
+			}
+		case <-ticker.C:
+			if err := c.saveCheckpoint(cp, tracker.seq()); err != nil {
+				runErr = err
+				break loop
+			}
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := c.saveCheckpoint(cp, tracker.seq()); err != nil && runErr == nil {
+		runErr = err
+	}
+	if handlerErr != nil {
+		return handlerErr
+	}
+	return runErr
+}
+
+// handle calls the handler and retries it with backoff.
+func (c *ChangesConsumer) handle(ctx context.Context, change Change) error {
+	options := StreamOptions{
+		MinBackoff: c.options.RetryBackoff,
+	}.withDefaults()
+	var err error
+	for attempt := 0; attempt <= c.options.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff(options, attempt)):
+			}
+		}
+		if err = c.handler(change); err == nil {
+			return nil
+		}
+	}
+	if c.options.DeadLetter != nil {
+		c.options.DeadLetter(change, err)
+		return nil
+	}
+	return err
+}
+
+func (c *ChangesConsumer) loadCheckpoint() (*checkpoint, error) {
+	cp := &checkpoint{}
+	if err := c.db.Get(cp, "_local/"+c.name); err != nil {
+		if cerr, ok := err.(*Error); ok && cerr.StatusCode == http.StatusNotFound {
+			return &checkpoint{
+				Document: Document{
+					ID: "_local/" + c.name,
+				},
+			}, nil
+		}
+		return nil, err
+	}
+	return cp, nil
+}
+
+// saveCheckpoint writes seq to the _local document if it has changed.
+func (c *ChangesConsumer) saveCheckpoint(cp *checkpoint, seq Seq) error {
+	if seq.IsZero() || seq == cp.Seq {
+		return nil
+	}
+	previous := cp.Seq
+	cp.Seq = seq
+	res, err := c.db.Put(cp)
+	if err != nil {
+		cp.Seq = previous
+		return err
+	}
+	cp.Rev = res.Rev
+	return nil
+}
+
+// seqTracker keeps changes in feed order and knows
+// the last sequence up to which all changes have been handled.
+type seqTracker struct {
+	mu      sync.Mutex
+	pending []*seqEntry
+	last    Seq
+}
+
+type seqEntry struct {
+	change Change
+	done   bool
+}
+
+func (t *seqTracker) add(change Change) *seqEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry := &seqEntry{change: change}
+	t.pending = append(t.pending, entry)
+	return entry
+}
+
+func (t *seqTracker) complete(entry *seqEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry.done = true
+	for len(t.pending) > 0 && t.pending[0].done {
+		// changes without sequence (seq_interval) do not move the checkpoint
+		if seq := t.pending[0].change.Seq; !seq.IsZero() {
+			t.last = seq
+		}
+		t.pending = t.pending[1:]
+	}
+}
+
+func (t *seqTracker) seq() Seq {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.last
+}
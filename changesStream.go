@@ -0,0 +1,157 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// ChangesStream consumes the continuous changes feed.
+// Changes are delivered on the channel returned by Changes or through Next.
+// The stream reconnects from the last seen sequence when the connection
+// breaks or stalls. Set ChangesParameters.Feed to FeedEventSource to
+// receive the same changes framed as server-sent events.
+//
+//	stream := db.StreamChanges(ctx, nil, StreamOptions{})
+//	defer stream.Close()
+//	for stream.Next() {
+//		change := stream.Change()
+//	}
+//	if err := stream.Err(); err != nil {
+//		// handle error
+//	}
+type ChangesStream struct {
+	changes chan Change
+	change  Change
+	cancel  context.CancelFunc
+	done    chan struct{}
+	once    sync.Once
+	// err is written before changes is closed
+	err error
+}
+
+// StreamChanges starts consuming the continuous changes feed.
+// The stream ends when the context is canceled, Close is called,
+// params.Limit changes have been received or reconnecting failed.
+//
+// http://docs.couchdb.org/en/latest/api/database/changes.html#continuous
+func (db *Database) StreamChanges(ctx context.Context, params *ChangesParameters, options StreamOptions) *ChangesStream {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s := &ChangesStream{
+		changes: make(chan Change),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	p := ChangesParameters{}
+	if params != nil {
+		p = *params
+	}
+	options = options.withDefaults()
+	framing := framingLines
+	if p.Feed == FeedEventSource {
+		framing = framingEventSource
+	} else {
+		p.Feed = FeedContinuous
+	}
+	p.Heartbeat = options.heartbeat()
+	connect := func(ctx context.Context) (*http.Response, error) {
+		header := http.Header{}
+		if framing == framingEventSource && !p.Since.IsZero() {
+			header.Set("Last-Event-ID", p.Since.String())
+		}
+		return db.changesRequest(ctx, &p, header)
+	}
+	handle := func(data []byte, id string) error {
+		var row struct {
+			Change
+			LastSeq Seq `json:"last_seq"`
+		}
+		if err := json.Unmarshal(data, &row); err != nil {
+			return err
+		}
+		// eventsource feeds carry the sequence in the event id
+		if row.Seq.IsZero() && id != "" {
+			row.Seq = NewSeq(id)
+		}
+		// the last line of a finished feed only contains the last sequence
+		if !row.LastSeq.IsZero() {
+			p.Since = row.LastSeq
+			if p.Limit != nil && *p.Limit <= 0 {
+				return errFeedDone
+			}
+			return nil
+		}
+		if !row.Seq.IsZero() {
+			p.Since = row.Seq
+		}
+		if p.Limit != nil {
+			remaining := *p.Limit - 1
+			p.Limit = &remaining
+		}
+		select {
+		case s.changes <- row.Change:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if p.Limit != nil && *p.Limit <= 0 {
+			return errFeedDone
+		}
+		return nil
+	}
+	go func() {
+		err := runFeed(ctx, options, framing, connect, handle)
+		if ctx.Err() != nil {
+			// closing the stream is not an error
+			err = nil
+		}
+		s.err = err
+		close(s.changes)
+		close(s.done)
+	}()
+	return s
+}
+
+// Changes returns the channel changes are delivered on.
+// It is closed when the stream ends.
+func (s *ChangesStream) Changes() <-chan Change {
+	return s.changes
+}
+
+// Next blocks until the next change is available.
+// It returns false when the stream has ended.
+func (s *ChangesStream) Next() bool {
+	change, ok := <-s.changes
+	if !ok {
+		return false
+	}
+	s.change = change
+	return true
+}
+
+// Change returns the current change.
+func (s *ChangesStream) Change() Change {
+	return s.change
+}
+
+// Err returns the error that ended the stream.
+// It is nil while the stream is running or after it was closed.
+func (s *ChangesStream) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Close stops the stream and waits until the connection is closed.
+// It is safe to call Close from another goroutine and more than once.
+func (s *ChangesStream) Close() error {
+	s.once.Do(s.cancel)
+	<-s.done
+	return nil
+}
@@ -2,6 +2,7 @@ package couchdb
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -324,6 +325,21 @@ func (c *Client) Replicate(req ReplicationRequest) (*ReplicationResponse, error)
 
 // Request creates new http request and does it.
 func (c *Client) Request(method, uri string, data io.Reader, contentType string) (*http.Response, error) {
+	return c.RequestContext(context.Background(), method, uri, data, contentType)
+}
+
+// RequestContext creates new http request with given context and does it.
+// Canceling the context aborts the request and closes the response body.
+func (c *Client) RequestContext(ctx context.Context, method, uri string, data io.Reader, contentType string) (*http.Response, error) {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	return c.requestHeader(ctx, method, uri, data, header)
+}
+
+// requestHeader creates new http request with given context and headers and does it.
+func (c *Client) requestHeader(ctx context.Context, method, uri string, data io.Reader, header http.Header) (*http.Response, error) {
 	rel, err := url.Parse(uri)
 	if err != nil {
 		return nil, err
@@ -333,8 +349,9 @@ func (c *Client) Request(method, uri string, data io.Reader, contentType string)
 	if err != nil {
 		return nil, err
 	}
-	if contentType != "" {
-		req.Header.Set("Content-Type", contentType)
+	req = req.WithContext(ctx)
+	for key, values := range header {
+		req.Header[key] = values
 	}
 	// basic auth
 	if c.Username != "" && c.Password != "" {
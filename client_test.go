@@ -2,16 +2,25 @@ package couchdb
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/google/go-querystring/query"
 	"github.com/segmentio/pointer"
 )
 
@@ -161,6 +170,55 @@ func TestCreateFail(t *testing.T) {
 	}
 }
 
+func TestWatchDBUpdates(t *testing.T) {
+	prefix, err := RandDBName(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events := make(chan DBUpdate, 10)
+	done := make(chan error)
+	go func() {
+		pattern := regexp.MustCompile("^" + prefix + "_")
+		done <- client.WatchDBUpdates(ctx, pattern, &DBUpdatesParameters{Since: NewSeq("now")}, StreamOptions{}, func(update DBUpdate) error {
+			events <- update
+			return nil
+		})
+	}()
+	// give the watcher some time to connect
+	time.Sleep(500 * time.Millisecond)
+	for _, name := range []string{"other_" + prefix, prefix + "_customer"} {
+		if _, err := client.Create(name); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.Delete(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	types := []string{}
+	for len(types) < 2 {
+		select {
+		case update := <-events:
+			if update.DBName != prefix+"_customer" {
+				t.Errorf("expected only events for %s_customer but got %s", prefix, update.DBName)
+			}
+			if update.Type == DBUpdateCreated || update.Type == DBUpdateDeleted {
+				types = append(types, update.Type)
+			}
+		case <-ctx.Done():
+			t.Fatalf("timeout waiting for events, got %v", types)
+		}
+	}
+	if !reflect.DeepEqual(types, []string{DBUpdateCreated, DBUpdateDeleted}) {
+		t.Errorf("expected created and deleted events but got %v", types)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
+
 func TestCreateUser(t *testing.T) {
 	name, err := RandDBName(5)
 	if err != nil {
@@ -895,6 +953,71 @@ func TestDocumentBulkDocs(t *testing.T) {
 	}
 }
 
+func TestBulkWriter(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	results := make(chan BulkResult, 100)
+	writer := NewBulkWriter(db, BulkWriterOptions{
+		BatchSize: 10,
+		Workers:   2,
+		Results:   results,
+	})
+	// add documents from multiple goroutines
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				doc := &DummyDocument{
+					Foo:  fmt.Sprintf("foo%d", i),
+					Beep: fmt.Sprintf("beep%d", j),
+				}
+				if err := writer.Add(doc); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 25 {
+		t.Errorf("expected 25 results after flush but got %d", len(results))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	close(results)
+	for result := range results {
+		if result.Err != nil {
+			t.Error(result.Err)
+		}
+		if !result.Response.Ok {
+			t.Errorf("expected ok to be true but got error %s", result.Response.Error)
+		}
+	}
+	if err := writer.Add(&DummyDocument{}); err != ErrBulkWriterClosed {
+		t.Errorf("expected ErrBulkWriterClosed but got %v", err)
+	}
+	res, err := db.AllDocs(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Rows) != 25 {
+		t.Errorf("expected 25 documents but got %d", len(res.Rows))
+	}
+}
+
 func TestAllDocs(t *testing.T) {
 	name, err := RandDBName(10)
 	if err != nil {
@@ -937,6 +1060,224 @@ func TestAllDocs(t *testing.T) {
 	}
 }
 
+func TestIterAllDocs(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	docs := make([]CouchDoc, 25)
+	for i := range docs {
+		docs[i] = &DummyDocument{
+			Document: Document{
+				ID: fmt.Sprintf("doc%02d", i),
+			},
+		}
+	}
+	if _, err := db.Bulk(docs); err != nil {
+		t.Fatal(err)
+	}
+	it := db.IterAllDocs(context.Background(), nil, 10)
+	ids := []string{}
+	for it.Next() {
+		ids = append(ids, it.Row().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 25 {
+		t.Fatalf("expected 25 rows but got %d", len(ids))
+	}
+	for i, id := range ids {
+		if id != fmt.Sprintf("doc%02d", i) {
+			t.Errorf("expected row %d to be doc%02d but got %s", i, i, id)
+		}
+	}
+	// canceled context stops iteration
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	it = db.IterAllDocs(ctx, nil, 10)
+	if it.Next() {
+		t.Error("expected no rows for canceled context")
+	}
+	if it.Err() != context.Canceled {
+		t.Errorf("expected context canceled error but got %v", it.Err())
+	}
+}
+
+func TestAllDocsKeys(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	doc := &DummyDocument{
+		Document: Document{
+			ID: "one",
+		},
+	}
+	if _, err := db.Put(doc); err != nil {
+		t.Fatal(err)
+	}
+	res, err := db.AllDocsKeys([]string{"one", "two"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Rows) != 2 {
+		t.Fatalf("expected 2 rows but got %d", len(res.Rows))
+	}
+	if res.Rows[0].ID != "one" || res.Rows[0].Error != "" {
+		t.Errorf("expected row for document one but got %+v", res.Rows[0])
+	}
+	if res.Rows[1].Error != "not_found" {
+		t.Errorf("expected not_found error but got %q", res.Rows[1].Error)
+	}
+}
+
+func TestAllDocsQueries(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	docs := make([]CouchDoc, 5)
+	for i := range docs {
+		docs[i] = &DummyDocument{
+			Document: Document{
+				ID: fmt.Sprintf("doc%d", i),
+			},
+		}
+	}
+	if _, err := db.Bulk(docs); err != nil {
+		t.Fatal(err)
+	}
+	results, err := db.AllDocsQueries([]QueryParameters{
+		{Keys: []interface{}{"doc1", "doc3"}},
+		{StartKey: "doc2", Limit: pointer.Int(10)},
+		{Limit: pointer.Int(1), Descending: pointer.Bool(true)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results but got %d", len(results))
+	}
+	if len(results[0].Rows) != 2 || len(results[1].Rows) != 3 || len(results[2].Rows) != 1 {
+		t.Fatalf("expected 2, 3 and 1 rows but got %d, %d and %d", len(results[0].Rows), len(results[1].Rows), len(results[2].Rows))
+	}
+	if results[2].Rows[0].ID != "doc4" {
+		t.Errorf("expected doc4 but got %s", results[2].Rows[0].ID)
+	}
+}
+
+func TestDeleteWhere(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	docs := make([]CouchDoc, 30)
+	for i := range docs {
+		docs[i] = &DummyDocument{
+			Document: Document{
+				ID: fmt.Sprintf("doc%02d", i),
+			},
+			Foo: fmt.Sprintf("foo%d", i%3),
+		}
+	}
+	if _, err := db.Bulk(docs); err != nil {
+		t.Fatal(err)
+	}
+	count := func() int {
+		res, err := db.AllDocs(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(res.Rows)
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		res, err := db.DeleteWhereRange("doc00", "doc09", DeleteOptions{DryRun: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Matched != 10 || res.Deleted != 0 {
+			t.Errorf("expected 10 matched and 0 deleted but got %d and %d", res.Matched, res.Deleted)
+		}
+		if n := count(); n != 30 {
+			t.Errorf("expected 30 documents but got %d", n)
+		}
+	})
+
+	t.Run("ids", func(t *testing.T) {
+		res, err := db.DeleteWhereIDs([]string{"doc00", "doc01", "unknown"}, DeleteOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Matched != 2 || res.Deleted != 2 {
+			t.Errorf("expected 2 matched and 2 deleted but got %d and %d", res.Matched, res.Deleted)
+		}
+	})
+
+	t.Run("range", func(t *testing.T) {
+		batches := 0
+		res, err := db.DeleteWhereRange("doc00", "doc09", DeleteOptions{
+			BatchSize: 3,
+			Progress: func(DeleteResult) {
+				batches++
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Deleted != 8 {
+			t.Errorf("expected 8 deleted documents but got %d", res.Deleted)
+		}
+		if batches != 3 {
+			t.Errorf("expected 3 batches but got %d", batches)
+		}
+		if n := count(); n != 20 {
+			t.Errorf("expected 20 documents but got %d", n)
+		}
+	})
+
+	t.Run("selector", func(t *testing.T) {
+		selector := map[string]interface{}{
+			"foo": "foo1",
+		}
+		res, err := db.DeleteWhereSelector(selector, DeleteOptions{BatchSize: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Deleted != 7 {
+			t.Errorf("expected 7 deleted documents but got %d", res.Deleted)
+		}
+		if n := count(); n != 13 {
+			t.Errorf("expected 13 documents but got %d", n)
+		}
+	})
+}
+
 func TestPurge(t *testing.T) {
 	dbName := "purge"
 	// create database
@@ -976,71 +1317,73 @@ func TestPurge(t *testing.T) {
 	}
 }
 
-func TestSecurity(t *testing.T) {
-	dbName := "sec"
+func TestRevsDiff(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
 	// create database
-	if _, err := client.Create(dbName); err != nil {
+	if _, err := client.Create(name); err != nil {
 		t.Error(err)
 	}
-	defer client.Delete(dbName)
-	db := client.Use(dbName)
-	// test putting security document first
-	secDoc := SecurityDocument{
-		Admins: Element{
-			Names: []string{
-				"admin1",
-			},
-			Roles: []string{
-				"",
-			},
-		},
-		Members: Element{
-			Names: []string{
-				"member1",
-			},
-			Roles: []string{
-				"",
-			},
-		},
+	defer client.Delete(name)
+	db := client.Use(name)
+	doc := &DummyDocument{
+		Foo:  "bar",
+		Beep: "bopp",
 	}
-	res, err := db.PutSecurity(secDoc)
+	postResponse, err := db.Post(doc)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
-	if !res.Ok {
-		t.Error("expected true but got false")
+	req := map[string][]string{
+		postResponse.ID: {postResponse.Rev, "2-abc"},
+		"missing":       {"1-abc"},
 	}
-	// test getting security document
-	doc, err := db.GetSecurity()
+	diff, err := db.RevsDiff(req)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
-	if doc.Admins.Names[0] != "admin1" {
-		t.Errorf("expected name admin1 but got %s instead", doc.Admins.Names[0])
+	if !reflect.DeepEqual(diff[postResponse.ID].Missing, []string{"2-abc"}) {
+		t.Errorf("expected missing revision 2-abc but got %v", diff[postResponse.ID].Missing)
 	}
-	if doc.Members.Names[0] != "member1" {
-		t.Errorf("expected name member1 but got %s instead", doc.Members.Names[0])
+	if !reflect.DeepEqual(diff[postResponse.ID].PossibleAncestors, []string{postResponse.Rev}) {
+		t.Errorf("expected possible ancestor %s but got %v", postResponse.Rev, diff[postResponse.ID].PossibleAncestors)
+	}
+	if !reflect.DeepEqual(diff["missing"].Missing, []string{"1-abc"}) {
+		t.Errorf("expected missing revision 1-abc but got %v", diff["missing"].Missing)
+	}
+	missing, err := db.MissingRevs(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(missing.MissingRevs[postResponse.ID], []string{"2-abc"}) {
+		t.Errorf("expected missing revision 2-abc but got %v", missing.MissingRevs[postResponse.ID])
 	}
 }
 
-// view tests
-type DataDocument struct {
-	Document
-	Type string `json:"type"`
-	Foo  string `json:"foo"`
-	Beep string `json:"beep"`
-	Age  int    `json:"age"`
-}
-
-type Person struct {
-	Document
-	Type   string  `json:"type"`
-	Name   string  `json:"name"`
-	Age    float64 `json:"age"`
-	Gender string  `json:"gender"`
+func TestChunkRevs(t *testing.T) {
+	req := map[string][]string{}
+	for i := 0; i < revsBatchSize*2+1; i++ {
+		req[fmt.Sprintf("doc%d", i)] = []string{"1-abc"}
+	}
+	chunks := chunkRevs(req)
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks but got %d", len(chunks))
+	}
+	total := 0
+	for _, chunk := range chunks {
+		if len(chunk) > revsBatchSize {
+			t.Errorf("expected at most %d entries but got %d", revsBatchSize, len(chunk))
+		}
+		total += len(chunk)
+	}
+	if total != len(req) {
+		t.Errorf("expected %d entries in total but got %d", len(req), total)
+	}
 }
 
-func TestView(t *testing.T) {
+func TestChanges(t *testing.T) {
 	name, err := RandDBName(10)
 	if err != nil {
 		t.Error(err)
@@ -1051,12 +1394,982 @@ func TestView(t *testing.T) {
 	}
 	defer client.Delete(name)
 	db := client.Use(name)
-	// create database
-	design := &DesignDocument{
-		Document: Document{
-			ID: "_design/test",
-		},
-		Language: langJavaScript,
+	docs := []CouchDoc{
+		&DummyDocument{Foo: "foo1"},
+		&DummyDocument{Foo: "foo2"},
+		&DummyDocument{Foo: "foo3"},
+	}
+	if _, err := db.Bulk(docs); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("normal", func(t *testing.T) {
+		res, err := db.Changes(&ChangesParameters{
+			IncludeDocs: pointer.Bool(true),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Results) != 3 {
+			t.Fatalf("expected 3 changes but got %d", len(res.Results))
+		}
+		if res.LastSeq.IsZero() {
+			t.Error("expected last seq to be set")
+		}
+		if res.Results[0].Doc["foo"] == nil {
+			t.Error("expected change to include document")
+		}
+	})
+
+	t.Run("since", func(t *testing.T) {
+		first, err := db.Changes(&ChangesParameters{
+			Limit: pointer.Int(1),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := db.Changes(&ChangesParameters{
+			Since: first.LastSeq,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Results) != 2 {
+			t.Errorf("expected 2 changes but got %d", len(res.Results))
+		}
+	})
+
+	t.Run("longpoll", func(t *testing.T) {
+		res, err := db.Changes(&ChangesParameters{
+			Feed:  FeedLongpoll,
+			Since: NewSeq("now"),
+			// return after 100ms without new changes
+			Timeout: pointer.Int(100),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Results) != 0 {
+			t.Errorf("expected no changes but got %d", len(res.Results))
+		}
+	})
+
+	t.Run("continuous", func(t *testing.T) {
+		if _, err := db.Changes(&ChangesParameters{Feed: FeedContinuous}); err == nil {
+			t.Error("expected error for continuous feed")
+		}
+	})
+}
+
+func TestChangesFilter(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	docs := []CouchDoc{
+		&animal{Document: Document{ID: "dog"}, Type: "animal", Animal: "dog", Owner: "john"},
+		&animal{Document: Document{ID: "cat"}, Type: "animal", Animal: "cat", Owner: "john"},
+		&animal{Document: Document{ID: "horse"}, Type: "animal", Animal: "horse", Owner: "steve"},
+	}
+	if _, err := db.Bulk(docs); err != nil {
+		t.Fatal(err)
+	}
+	designDocument := &DesignDocument{
+		Document: Document{
+			ID: "_design/animals",
+		},
+		Language: langJavaScript,
+		Views: map[string]DesignDocumentView{
+			"byOwner": {
+				Map: "function(doc) { if (doc.owner === 'steve') { emit(doc.owner) } }",
+			},
+		},
+		Filters: map[string]string{
+			"byOwner": "function(doc, req) { return doc.owner === req.query.owner }",
+		},
+	}
+	if _, err := db.Put(designDocument); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		desc   string
+		params ChangesParameters
+		ids    []string
+	}{
+		{
+			desc:   "doc ids",
+			params: ChangesParameters{DocIDs: []string{"dog", "horse"}},
+			ids:    []string{"dog", "horse"},
+		},
+		{
+			desc:   "selector",
+			params: ChangesParameters{Selector: map[string]interface{}{"animal": "cat"}},
+			ids:    []string{"cat"},
+		},
+		{
+			desc:   "view",
+			params: ChangesParameters{View: "animals/byOwner"},
+			ids:    []string{"horse"},
+		},
+		{
+			desc: "design document filter",
+			params: ChangesParameters{
+				Filter: "animals/byOwner",
+				Query:  map[string]string{"owner": "john"},
+			},
+			ids: []string{"cat", "dog"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			res, err := db.Changes(&tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := []string{}
+			for _, change := range res.Results {
+				ids = append(ids, change.ID)
+			}
+			sort.Strings(ids)
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("expected %v but got %v", tt.ids, ids)
+			}
+		})
+	}
+	t.Run("continuous", func(t *testing.T) {
+		stream := db.StreamChanges(context.Background(), &ChangesParameters{
+			DocIDs: []string{"horse"},
+			Limit:  pointer.Int(1),
+		}, StreamOptions{})
+		if !stream.Next() {
+			t.Fatal(stream.Err())
+		}
+		if stream.Change().ID != "horse" {
+			t.Errorf("expected change for horse but got %s", stream.Change().ID)
+		}
+		stream.Close()
+	})
+}
+
+func TestStreamChanges(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	stream := db.StreamChanges(context.Background(), nil, StreamOptions{
+		Heartbeat: 100 * time.Millisecond,
+	})
+	defer stream.Close()
+	for _, foo := range []string{"foo1", "foo2", "foo3"} {
+		if _, err := db.Post(&DummyDocument{Foo: foo}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case change := <-stream.Changes():
+			if change.ID == "" {
+				t.Error("expected change to have an id")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for change")
+		}
+	}
+	// close from another goroutine
+	go stream.Close()
+	for stream.Next() {
+		t.Error("expected no more changes after close")
+	}
+	if err := stream.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStreamChangesEventSource(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	docs := []CouchDoc{
+		&DummyDocument{Document: Document{ID: "one"}},
+		&DummyDocument{Document: Document{ID: "two"}},
+	}
+	if _, err := db.Bulk(docs); err != nil {
+		t.Fatal(err)
+	}
+	ids := map[string][]string{}
+	for _, feed := range []string{FeedContinuous, FeedEventSource} {
+		stream := db.StreamChanges(context.Background(), &ChangesParameters{
+			Feed:  feed,
+			Limit: pointer.Int(2),
+		}, StreamOptions{})
+		for stream.Next() {
+			change := stream.Change()
+			if change.Seq.IsZero() {
+				t.Errorf("%s: expected change to have a sequence", feed)
+			}
+			ids[feed] = append(ids[feed], change.ID)
+		}
+		if err := stream.Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(ids[FeedContinuous], ids[FeedEventSource]) {
+		t.Errorf("expected same changes for both feeds but got %v and %v", ids[FeedContinuous], ids[FeedEventSource])
+	}
+}
+
+func TestStreamChangesLimit(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	docs := []CouchDoc{
+		&DummyDocument{Foo: "foo1"},
+		&DummyDocument{Foo: "foo2"},
+		&DummyDocument{Foo: "foo3"},
+	}
+	if _, err := db.Bulk(docs); err != nil {
+		t.Fatal(err)
+	}
+	stream := db.StreamChanges(context.Background(), &ChangesParameters{
+		Limit: pointer.Int(2),
+	}, StreamOptions{})
+	n := 0
+	for stream.Next() {
+		n++
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 changes but got %d", n)
+	}
+}
+
+func TestChangesConsumer(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	docs := make([]CouchDoc, 10)
+	for i := range docs {
+		docs[i] = &DummyDocument{
+			Document: Document{
+				ID: fmt.Sprintf("doc%d", i),
+			},
+		}
+	}
+	if _, err := db.Bulk(docs); err != nil {
+		t.Fatal(err)
+	}
+	run := func(handler func(Change) error, options ConsumerOptions) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		consumer := NewChangesConsumer(db, "test", handler, options)
+		return consumer.Run(ctx)
+	}
+
+	t.Run("dead letter", func(t *testing.T) {
+		var mu sync.Mutex
+		handled := map[string]int{}
+		dead := []string{}
+		err := run(func(change Change) error {
+			mu.Lock()
+			defer mu.Unlock()
+			handled[change.ID]++
+			if change.ID == "doc3" {
+				return fmt.Errorf("cannot handle %s", change.ID)
+			}
+			return nil
+		}, ConsumerOptions{
+			Workers:      3,
+			MaxRetries:   2,
+			RetryBackoff: time.Millisecond,
+			DeadLetter: func(change Change, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				dead = append(dead, change.ID)
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(handled) != 10 {
+			t.Errorf("expected 10 handled documents but got %d", len(handled))
+		}
+		if handled["doc3"] != 3 {
+			t.Errorf("expected doc3 to be handled 3 times but got %d", handled["doc3"])
+		}
+		if !reflect.DeepEqual(dead, []string{"doc3"}) {
+			t.Errorf("expected doc3 as dead letter but got %v", dead)
+		}
+	})
+
+	t.Run("resume from checkpoint", func(t *testing.T) {
+		consumer := NewChangesConsumer(db, "test", nil, ConsumerOptions{})
+		seq, err := consumer.Checkpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seq.IsZero() {
+			t.Fatal("expected checkpoint to be stored")
+		}
+		if _, err := db.Post(&DummyDocument{Foo: "new"}); err != nil {
+			t.Fatal(err)
+		}
+		handled := 0
+		err = run(func(change Change) error {
+			handled++
+			return nil
+		}, ConsumerOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if handled != 1 {
+			t.Errorf("expected only the new document to be handled but got %d", handled)
+		}
+	})
+
+	t.Run("handler error", func(t *testing.T) {
+		if _, err := db.Post(&DummyDocument{Foo: "fail"}); err != nil {
+			t.Fatal(err)
+		}
+		err := run(func(change Change) error {
+			return fmt.Errorf("cannot handle %s", change.ID)
+		}, ConsumerOptions{})
+		if err == nil {
+			t.Fatal("expected handler error")
+		}
+	})
+}
+
+func TestSeqTracker(t *testing.T) {
+	tracker := &seqTracker{}
+	entries := []*seqEntry{}
+	for _, seq := range []string{"1", "2", "", "4"} {
+		entries = append(entries, tracker.add(Change{Seq: NewSeq(seq)}))
+	}
+	tracker.complete(entries[1])
+	if seq := tracker.seq(); !seq.IsZero() {
+		t.Errorf("expected no sequence while first change is pending but got %s", seq)
+	}
+	tracker.complete(entries[0])
+	if seq := tracker.seq(); seq.String() != "2" {
+		t.Errorf("expected sequence 2 but got %s", seq)
+	}
+	tracker.complete(entries[2])
+	if seq := tracker.seq(); seq.String() != "2" {
+		t.Errorf("expected sequence 2 but got %s", seq)
+	}
+	tracker.complete(entries[3])
+	if seq := tracker.seq(); seq.String() != "4" {
+		t.Errorf("expected sequence 4 but got %s", seq)
+	}
+}
+
+func TestRunFeed(t *testing.T) {
+	options := StreamOptions{
+		StallTimeout: 50 * time.Millisecond,
+		MinBackoff:   time.Millisecond,
+	}.withDefaults()
+	bodies := []io.Reader{
+		// first connection breaks after one line
+		strings.NewReader("a\n\nb\n"),
+		// second connection stalls
+		io.MultiReader(strings.NewReader("c\n"), blockingReader{}),
+		strings.NewReader("\nd\n"),
+	}
+	connections := 0
+	connect := func(ctx context.Context) (*http.Response, error) {
+		body := bodies[connections]
+		connections++
+		return &http.Response{
+			Body: ioutil.NopCloser(contextReader{ctx, body}),
+		}, nil
+	}
+	lines := []string{}
+	handle := func(line []byte, id string) error {
+		lines = append(lines, string(line))
+		if string(line) == "d" {
+			return errFeedDone
+		}
+		return nil
+	}
+	if err := runFeed(context.Background(), options, framingLines, connect, handle); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lines, []string{"a", "b", "c", "d"}) {
+		t.Errorf("expected lines a, b, c, d but got %v", lines)
+	}
+	if connections != 3 {
+		t.Errorf("expected 3 connections but got %d", connections)
+	}
+}
+
+func TestRunFeedEventSource(t *testing.T) {
+	options := StreamOptions{}.withDefaults()
+	body := ": heartbeat\n\n" +
+		"data: {\"seq\":1}\nid: 1\n\n" +
+		"\n" +
+		"data: {\"a\":\ndata: 2}\nid: 2\n\n" +
+		"data: incomplete\n"
+	connect := func(ctx context.Context) (*http.Response, error) {
+		return &http.Response{
+			Body: ioutil.NopCloser(strings.NewReader(body)),
+		}, nil
+	}
+	events := []string{}
+	handle := func(data []byte, id string) error {
+		events = append(events, id+" "+string(data))
+		if id == "2" {
+			return errFeedDone
+		}
+		return nil
+	}
+	if err := runFeed(context.Background(), options, framingEventSource, connect, handle); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{`1 {"seq":1}`, "2 {\"a\":\n2}"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected events %q but got %q", expected, events)
+	}
+}
+
+// blockingReader never returns any data.
+type blockingReader struct{}
+
+func (blockingReader) Read(p []byte) (int, error) {
+	select {}
+}
+
+// contextReader stops reading when the context is canceled
+// like the body of a http response does.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	type result struct {
+		n   int
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		n, err := c.r.Read(p)
+		ch <- result{n, err}
+	}()
+	select {
+	case res := <-ch:
+		return res.n, res.err
+	case <-c.ctx.Done():
+		return 0, c.ctx.Err()
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	options := StreamOptions{
+		MinBackoff: time.Second,
+		MaxBackoff: 5 * time.Second,
+	}
+	tests := []struct {
+		failures int
+		wait     time.Duration
+	}{
+		{0, time.Second},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{10, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if wait := backoff(options, tt.failures); wait != tt.wait {
+			t.Errorf("backoff(%d): expected %s but got %s", tt.failures, tt.wait, wait)
+		}
+	}
+}
+
+func TestSeq(t *testing.T) {
+	tests := []struct {
+		in  string
+		seq string
+	}{
+		{`42`, "42"},
+		{`"42-g1AAAAFTeJzLYWBg4MhgTmHgz8tPSTV0MDQy"`, "42-g1AAAAFTeJzLYWBg4MhgTmHgz8tPSTV0MDQy"},
+		// numeric looking strings stay strings
+		{`"0"`, "0"},
+		{`[23,"g1AAAAFTeJzLYWBg"]`, `[23,"g1AAAAFTeJzLYWBg"]`},
+		{`null`, ""},
+	}
+	for _, tt := range tests {
+		var seq Seq
+		if err := json.Unmarshal([]byte(tt.in), &seq); err != nil {
+			t.Fatal(err)
+		}
+		if seq.String() != tt.seq {
+			t.Errorf("unmarshal %s: expected %q but got %q", tt.in, tt.seq, seq)
+		}
+		// sequences are written back in their original form
+		b, err := json.Marshal(seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.in {
+			t.Errorf("marshal %q: expected %s but got %s", seq, tt.in, b)
+		}
+	}
+	b, err := json.Marshal(NewSeq("7"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `"7"` {
+		t.Errorf("expected string sequence but got %s", b)
+	}
+	q, err := query.Values(ChangesParameters{Since: NewSeq("now")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Encode() != "since=now" {
+		t.Errorf("unexpected query %s", q.Encode())
+	}
+	q, err = query.Values(ChangesParameters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Encode() != "" {
+		t.Errorf("expected empty sequence to be omitted but got %s", q.Encode())
+	}
+}
+
+func TestFind(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	docs := []CouchDoc{
+		&Person{Type: "person", Name: "Alice", Age: 31, Gender: "female"},
+		&Person{Type: "person", Name: "Bob", Age: 25, Gender: "male"},
+		&Person{Type: "person", Name: "Carol", Age: 47, Gender: "female"},
+		&DummyDocument{Foo: "foo"},
+	}
+	if _, err := db.Bulk(docs); err != nil {
+		t.Fatal(err)
+	}
+	people := []Person{}
+	res, err := db.Find(FindQuery{
+		Selector: And(
+			Eq("type", "person"),
+			Gt("age", 30),
+			Or(Eq("gender", "female"), Regex("name", "^B")),
+		),
+		Fields:         []string{"_id", "name", "age"},
+		ExecutionStats: true,
+	}, &people)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(people) != 2 {
+		t.Fatalf("expected 2 people but got %d", len(people))
+	}
+	for _, p := range people {
+		if p.Age <= 30 {
+			t.Errorf("expected age greater than 30 but got %v", p.Age)
+		}
+		if p.Gender != "" {
+			t.Errorf("expected gender not to be returned but got %s", p.Gender)
+		}
+	}
+	// querying without index returns a warning
+	if res.Warning == "" {
+		t.Error("expected warning about missing index")
+	}
+	if res.ExecutionStats == nil || res.ExecutionStats.ResultsReturned != 2 {
+		t.Errorf("expected execution stats with 2 results but got %+v", res.ExecutionStats)
+	}
+}
+
+func TestIndexes(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	index := IndexDefinition{
+		Index: Index{
+			Fields:                []map[string]string{Asc("age"), Asc("name")},
+			PartialFilterSelector: Eq("type", "person"),
+		},
+		DDoc: "people",
+		Name: "by-age",
+		Type: IndexTypeJSON,
+	}
+	res, err := db.CreateIndex(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Result != "created" || res.ID != "_design/people" || res.Name != "by-age" {
+		t.Errorf("unexpected create response %+v", res)
+	}
+	// creating the same index again is fine
+	res, err = db.CreateIndex(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Result != "exists" {
+		t.Errorf("expected result exists but got %s", res.Result)
+	}
+	list, err := db.ListIndexes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// _all_docs and by-age
+	if list.TotalRows != 2 || len(list.Indexes) != 2 {
+		t.Fatalf("expected 2 indexes but got %+v", list)
+	}
+	if list.Indexes[0].Type != IndexTypeSpecial || list.Indexes[0].DDoc != "" {
+		t.Errorf("expected special _all_docs index but got %+v", list.Indexes[0])
+	}
+	info := list.Indexes[1]
+	if info.DDoc != "_design/people" || info.Name != "by-age" || info.Type != IndexTypeJSON {
+		t.Errorf("unexpected index %+v", info)
+	}
+	if !reflect.DeepEqual(info.Def.Fields, index.Index.Fields) {
+		t.Errorf("expected fields %v but got %v", index.Index.Fields, info.Def.Fields)
+	}
+	if info.Def.PartialFilterSelector == nil {
+		t.Error("expected partial filter selector")
+	}
+	if _, err := db.DeleteIndex("_design/people", IndexTypeJSON, "by-age"); err != nil {
+		t.Fatal(err)
+	}
+	list, err = db.ListIndexes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Indexes) != 1 {
+		t.Errorf("expected only _all_docs index but got %+v", list.Indexes)
+	}
+}
+
+func TestDeleteTextIndex(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	index := IndexDefinition{
+		Index: Index{
+			Fields: []map[string]string{{"name": "string"}},
+		},
+		DDoc: "people",
+		Name: "by-name",
+		Type: IndexTypeText,
+	}
+	// text indexes need CouchDB with the search plugin
+	if _, err := db.CreateIndex(index); err != nil {
+		t.Skipf("text indexes are not supported: %v", err)
+	}
+	list, err := db.ListIndexes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Indexes) != 2 || list.Indexes[1].Type != IndexTypeText {
+		t.Fatalf("expected text index but got %+v", list.Indexes)
+	}
+	if _, err := db.DeleteIndex("people", IndexTypeText, "by-name"); err != nil {
+		t.Fatal(err)
+	}
+	list, err = db.ListIndexes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Indexes) != 1 {
+		t.Errorf("expected only _all_docs index but got %+v", list.Indexes)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	query := FindQuery{
+		Selector: Gt("age", 30),
+	}
+	// without index
+	plan, err := db.Explain(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.UsesAllDocs() {
+		t.Errorf("expected _all_docs index but got %+v", plan.Index)
+	}
+	// with index
+	if _, err := db.CreateIndex(IndexDefinition{
+		Index: Index{
+			Fields: []map[string]string{Asc("age")},
+		},
+		DDoc: "people",
+		Name: "by-age",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	plan, err = db.Explain(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.UsesAllDocs() {
+		t.Error("expected query to use index")
+	}
+	if plan.Index.Name != "by-age" || plan.Index.DDoc != "_design/people" {
+		t.Errorf("expected index by-age but got %+v", plan.Index)
+	}
+	if plan.DBName != name {
+		t.Errorf("expected db name %s but got %s", name, plan.DBName)
+	}
+	if plan.Range.Direction != "fwd" {
+		t.Errorf("expected direction fwd but got %s", plan.Range.Direction)
+	}
+}
+
+func TestIterFind(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	docs := []CouchDoc{}
+	for i := 0; i < 25; i++ {
+		docs = append(docs, &Person{Type: "person", Age: float64(i)})
+	}
+	docs = append(docs, &DummyDocument{Foo: "foo"})
+	if _, err := db.Bulk(docs); err != nil {
+		t.Fatal(err)
+	}
+	query := FindQuery{
+		Selector: Eq("type", "person"),
+	}
+	it := db.IterFind(context.Background(), query, 10)
+	count := 0
+	for it.Next() {
+		var p Person
+		if err := it.Decode(&p); err != nil {
+			t.Fatal(err)
+		}
+		if p.Type != "person" {
+			t.Errorf("expected person but got %+v", p)
+		}
+		count++
+		// resume from the bookmark after the first page
+		if count == 10 {
+			break
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	query.Bookmark = it.Bookmark()
+	it = db.IterFind(context.Background(), query, 10)
+	for it.Next() {
+		count++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if count != 25 {
+		t.Errorf("expected 25 documents but got %d", count)
+	}
+	// limit
+	limit := 12
+	query = FindQuery{
+		Selector: Eq("type", "person"),
+		Limit:    &limit,
+	}
+	it = db.IterFind(context.Background(), query, 5)
+	count = 0
+	for it.Next() {
+		count++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if count != 12 {
+		t.Errorf("expected 12 documents but got %d", count)
+	}
+}
+
+func TestSelector(t *testing.T) {
+	tests := []struct {
+		selector Selector
+		json     string
+	}{
+		{Eq("name", "Alice"), `{"name":{"$eq":"Alice"}}`},
+		{Gt("age", 21), `{"age":{"$gt":21}}`},
+		{In("tags", "a", "b"), `{"tags":{"$in":["a","b"]}}`},
+		{Exists("nickname", false), `{"nickname":{"$exists":false}}`},
+		{Type("age", "number"), `{"age":{"$type":"number"}}`},
+		{Size("tags", 2), `{"tags":{"$size":2}}`},
+		{Mod("age", 2, 1), `{"age":{"$mod":[2,1]}}`},
+		{Regex("name", "^A"), `{"name":{"$regex":"^A"}}`},
+		{Not(Eq("name", "Bob")), `{"$not":{"name":{"$eq":"Bob"}}}`},
+		{ElemMatch("genres", Eq("name", "Horror")), `{"genres":{"$elemMatch":{"name":{"$eq":"Horror"}}}}`},
+		{
+			And(Eq("type", "user"), Or(Lt("age", 18), Gte("age", 65))),
+			`{"$and":[{"type":{"$eq":"user"}},{"$or":[{"age":{"$lt":18}},{"age":{"$gte":65}}]}]}`,
+		},
+		{And(), `{"$and":[]}`},
+	}
+	for _, tt := range tests {
+		b, err := json.Marshal(tt.selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.json {
+			t.Errorf("expected %s but got %s", tt.json, b)
+		}
+	}
+}
+
+func TestSecurity(t *testing.T) {
+	dbName := "sec"
+	// create database
+	if _, err := client.Create(dbName); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(dbName)
+	db := client.Use(dbName)
+	// test putting security document first
+	secDoc := SecurityDocument{
+		Admins: Element{
+			Names: []string{
+				"admin1",
+			},
+			Roles: []string{
+				"",
+			},
+		},
+		Members: Element{
+			Names: []string{
+				"member1",
+			},
+			Roles: []string{
+				"",
+			},
+		},
+	}
+	res, err := db.PutSecurity(secDoc)
+	if err != nil {
+		t.Error(err)
+	}
+	if !res.Ok {
+		t.Error("expected true but got false")
+	}
+	// test getting security document
+	doc, err := db.GetSecurity()
+	if err != nil {
+		t.Error(err)
+	}
+	if doc.Admins.Names[0] != "admin1" {
+		t.Errorf("expected name admin1 but got %s instead", doc.Admins.Names[0])
+	}
+	if doc.Members.Names[0] != "member1" {
+		t.Errorf("expected name member1 but got %s instead", doc.Members.Names[0])
+	}
+}
+
+// view tests
+type DataDocument struct {
+	Document
+	Type string `json:"type"`
+	Foo  string `json:"foo"`
+	Beep string `json:"beep"`
+	Age  int    `json:"age"`
+}
+
+type Person struct {
+	Document
+	Type   string  `json:"type"`
+	Name   string  `json:"name"`
+	Age    float64 `json:"age"`
+	Gender string  `json:"gender"`
+}
+
+func TestView(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	// create database
+	design := &DesignDocument{
+		Document: Document{
+			ID: "_design/test",
+		},
+		Language: langJavaScript,
 		Views: map[string]DesignDocumentView{
 			"foo": {
 				Map: `
@@ -1085,227 +2398,1243 @@ func TestView(t *testing.T) {
 					}
 				`,
 			},
-		},
+		},
+	}
+	if _, err := db.Post(design); err != nil {
+		t.Fatal(err)
+	}
+	// create design document for person
+	designPerson := DesignDocument{
+		Document: Document{
+			ID: "_design/person",
+		},
+		Language: langJavaScript,
+		Views: map[string]DesignDocumentView{
+			"ageByGender": {
+				Map: `
+					function(doc) {
+						if (doc.type === 'person') {
+							emit(doc.gender, doc.age);
+						}
+					}
+				`,
+				Reduce: `
+					function(keys, values, rereduce) {
+						return sum(values);
+					}
+				`,
+			},
+		},
+	}
+	if _, err := db.Post(&designPerson); err != nil {
+		t.Fatal(err)
+	}
+	// create dummy data
+	doc1 := &DataDocument{
+		Type: "data",
+		Foo:  "foo1",
+		Beep: "beep1",
+		Age:  10,
+	}
+	if _, err := db.Post(doc1); err != nil {
+		t.Fatal(err)
+	}
+	doc2 := &DataDocument{
+		Type: "data",
+		Foo:  "foo2",
+		Beep: "beep2",
+		Age:  20,
+	}
+	if _, err := db.Post(doc2); err != nil {
+		t.Fatal(err)
+	}
+	// create multiple persons
+	data := []struct {
+		Name   string
+		Age    float64
+		Gender string
+	}{
+		{"John", 45, "male"},
+		{"Frank", 40, "male"},
+		{"Steve", 60, "male"},
+		{"Max", 26, "male"},
+		{"Marc", 36, "male"},
+		{"Nick", 18, "male"},
+		{"Jessica", 49, "female"},
+		{"Lily", 20, "female"},
+		{"Sophia", 66, "female"},
+		{"Chloe", 12, "female"},
+	}
+	people := make([]CouchDoc, len(data))
+	for index, d := range data {
+		people[index] = &Person{
+			Type:   "person",
+			Name:   d.Name,
+			Age:    d.Age,
+			Gender: d.Gender,
+		}
+	}
+	// bulk save people to database
+	if _, err := db.Bulk(people); err != nil {
+		t.Fatal(err)
+	}
+
+	// run sub test
+	t.Run("get", func(t *testing.T) {
+		view := db.View("test")
+		params := QueryParameters{}
+		res, err := view.Get("foo", params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.TotalRows != 2 {
+			t.Errorf("expected total rows to be 2 but got %d", res.TotalRows)
+		}
+		if res.Offset != 0 {
+			t.Errorf("expected offset to be 0 but got %d", res.Offset)
+		}
+	})
+
+	t.Run("design document name", func(t *testing.T) {
+		doc := new(DesignDocument)
+		err := db.Get(doc, "_design/test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.Name() != "test" {
+			t.Errorf("expected name to be test but got %s", doc.Name())
+		}
+	})
+
+	t.Run("design document view", func(t *testing.T) {
+		doc := new(DesignDocument)
+		err := db.Get(doc, "_design/test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := doc.Views["foo"]; !ok {
+			t.Error("expected foo mapping function to exists but it does not")
+		}
+	})
+
+	t.Run("get with query parameters", func(t *testing.T) {
+		view := db.View("test")
+		params := QueryParameters{
+			Key: "foo1",
+		}
+		res, err := view.Get("foo", params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Rows) != 1 {
+			t.Errorf("expected only one row but got %d", len(res.Rows))
+		}
+	})
+
+	t.Run("get with start and end key", func(t *testing.T) {
+		view := db.View("test")
+		params := QueryParameters{
+			StartKey: []string{"foo2", "beep2"},
+			EndKey:   []string{"foo2", "beep2"},
+		}
+		res, err := view.Get("complex", params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Rows) != 1 {
+			t.Errorf("expected only one row but got %d", len(res.Rows))
+		}
+	})
+
+	t.Run("get with integer", func(t *testing.T) {
+		view := db.View("test")
+		params := QueryParameters{
+			StartKey: []interface{}{"foo2", 20},
+			EndKey:   []interface{}{"foo2", 20},
+		}
+		res, err := view.Get("int", params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Rows) != 1 {
+			t.Errorf("expected only one row but got %d", len(res.Rows))
+		}
+	})
+
+	t.Run("get with reduce", func(t *testing.T) {
+		view := db.View("person")
+		params := QueryParameters{}
+		res, err := view.Get("ageByGender", params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ageTotalSum := res.Rows[0].Value.(float64)
+		if ageTotalSum != 372 {
+			t.Fatalf("expected age 372 but got %v", ageTotalSum)
+		}
+	})
+
+	t.Run("get with reduce and group", func(t *testing.T) {
+		view := db.View("person")
+		params := QueryParameters{
+			Key:        "female",
+			GroupLevel: pointer.Int(1),
+		}
+		res, err := view.Get("ageByGender", params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ageTotalFemale := res.Rows[0].Value.(float64)
+		if ageTotalFemale != 147 {
+			t.Fatalf("expected age 147 but got %v", ageTotalFemale)
+		}
+	})
+
+	t.Run("get without reduce", func(t *testing.T) {
+		view := db.View("person")
+		params := QueryParameters{
+			Key:    "male",
+			Reduce: pointer.Bool(false),
+		}
+		res, err := view.Get("ageByGender", params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Rows) != 6 {
+			t.Fatalf("expected 6 rows but got %d instead", len(res.Rows))
+		}
+	})
+
+	t.Run("post", func(t *testing.T) {
+		view := db.View("person")
+		params := QueryParameters{
+			Reduce: pointer.Bool(false),
+		}
+		res, err := view.Post("ageByGender", []interface{}{"male"}, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Rows) != 6 {
+			t.Fatalf("expected 6 rows but got %d instead", len(res.Rows))
+		}
+	})
+
+	t.Run("get rows", func(t *testing.T) {
+		view := db.View("person")
+		params := QueryParameters{
+			Key:         "female",
+			Reduce:      pointer.Bool(false),
+			IncludeDocs: pointer.Bool(true),
+		}
+		rows := []struct {
+			ID    string  `json:"id"`
+			Key   string  `json:"key"`
+			Value int     `json:"value"`
+			Doc   *Person `json:"doc"`
+		}{}
+		res, err := view.GetRows("ageByGender", params, &rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.TotalRows != 10 {
+			t.Errorf("expected 10 total rows but got %d", res.TotalRows)
+		}
+		if len(rows) != 4 {
+			t.Fatalf("expected 4 rows but got %d", len(rows))
+		}
+		for _, row := range rows {
+			if row.Key != "female" || row.Doc == nil || row.Doc.Gender != "female" || row.Value != int(row.Doc.Age) {
+				t.Errorf("unexpected row %+v", row)
+			}
+		}
+	})
+
+	t.Run("post rows with missing key", func(t *testing.T) {
+		view := db.View("test")
+		rows := []RawRow{}
+		_, err := view.PostRows("complex", []interface{}{
+			[]string{"foo9", "beep9"},
+			[]string{"foo1", "beep1"},
+		}, QueryParameters{}, &rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// views omit missing keys
+		if len(rows) != 1 {
+			t.Fatalf("expected 1 row but got %d", len(rows))
+		}
+		var key []string
+		if err := json.Unmarshal(rows[0].Key, &key); err != nil {
+			t.Fatal(err)
+		}
+		if key[0] != "foo1" || rows[0].Error != "" {
+			t.Errorf("expected row for key foo1 but got %+v", rows[0])
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		view := db.View("person")
+		params := QueryParameters{
+			Reduce:    pointer.Bool(false),
+			UpdateSeq: pointer.Bool(true),
+		}
+		stream, err := view.Stream(context.Background(), "ageByGender", params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stream.Close()
+		count := 0
+		for stream.Next() {
+			row, err := stream.Row()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if row.Key == nil {
+				t.Errorf("expected row with key but got %+v", row)
+			}
+			count++
+		}
+		if err := stream.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if count != 10 || stream.TotalRows != 10 {
+			t.Errorf("expected 10 rows but got %d of %d", count, stream.TotalRows)
+		}
+		if stream.UpdateSeq.IsZero() {
+			t.Error("expected update seq")
+		}
+	})
+
+	t.Run("queries", func(t *testing.T) {
+		view := db.View("person")
+		results, err := view.Queries("ageByGender", []QueryParameters{
+			{Key: "female", Reduce: pointer.Bool(false)},
+			{Keys: []interface{}{"male"}, Reduce: pointer.Bool(false), Limit: pointer.Int(2)},
+			{Group: pointer.Bool(true)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 3 {
+			t.Fatalf("expected 3 results but got %d", len(results))
+		}
+		if len(results[0].Rows) != 4 || len(results[1].Rows) != 2 || len(results[2].Rows) != 2 {
+			t.Errorf("expected 4, 2 and 2 rows but got %d, %d and %d", len(results[0].Rows), len(results[1].Rows), len(results[2].Rows))
+		}
+	})
+
+	t.Run("info", func(t *testing.T) {
+		info, err := db.View("person").Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Name != "person" {
+			t.Errorf("expected name person but got %s", info.Name)
+		}
+		if info.ViewIndex.Signature == "" || info.ViewIndex.Language != langJavaScript {
+			t.Errorf("unexpected view index %+v", info.ViewIndex)
+		}
+		// views were queried so the index is not empty
+		if info.ViewIndex.Sizes.File == 0 && info.ViewIndex.DiskSize == 0 {
+			t.Errorf("expected index size but got %+v", info.ViewIndex)
+		}
+	})
+
+	t.Run("compact and cleanup", func(t *testing.T) {
+		res, err := db.CompactView("person")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Ok {
+			t.Error("expected compaction to start")
+		}
+		if res, err = db.ViewCleanup(); err != nil {
+			t.Fatal(err)
+		}
+		if !res.Ok {
+			t.Error("expected view cleanup to start")
+		}
+	})
+
+	t.Run("get with keys", func(t *testing.T) {
+		view := db.View("test")
+		params := QueryParameters{
+			Keys: []interface{}{
+				[]string{"foo1", "beep1"},
+				[]string{"foo2", "beep2"},
+				[]string{"foo3", "beep3"},
+			},
+		}
+		res, err := view.Get("complex", params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Rows) != 2 {
+			t.Errorf("expected two rows but got %d", len(res.Rows))
+		}
+	})
+
+	t.Run("get with array prefix range", func(t *testing.T) {
+		view := db.View("test")
+		start, end := ArrayPrefixRange("foo2")
+		params := QueryParameters{
+			StartKey: start,
+			EndKey:   end,
+		}
+		res, err := view.Get("complex", params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Rows) != 1 {
+			t.Errorf("expected only one row but got %d", len(res.Rows))
+		}
+	})
+
+	t.Run("get with prefix range", func(t *testing.T) {
+		view := db.View("test")
+		start, end := PrefixRange("foo")
+		params := QueryParameters{
+			StartKey: start,
+			EndKey:   end,
+		}
+		res, err := view.Get("foo", params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Rows) != 2 {
+			t.Errorf("expected two rows but got %d", len(res.Rows))
+		}
+	})
+
+}
+
+func TestViewStream(t *testing.T) {
+	body := `{"total_rows":3,"offset":1,"update_seq":"12-abc","rows":[
+		{"id":"a","key":["x",1],"value":1},
+		{"id":"b","key":["x",2],"value":{"n":2}}
+	],"extra":{"ignored":[1,2]}}`
+	s := newViewStream(ioutil.NopCloser(strings.NewReader(body)))
+	rows := []Row{}
+	for s.Next() {
+		if s.TotalRows != 3 || s.Offset != 1 || s.UpdateSeq.String() != "12-abc" {
+			t.Errorf("expected meta data before first row but got %d %d %s", s.TotalRows, s.Offset, s.UpdateSeq)
+		}
+		row, err := s.Row()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, row)
 	}
-	if _, err := db.Post(design); err != nil {
+	if err := s.Err(); err != nil {
 		t.Fatal(err)
 	}
-	// create design document for person
-	designPerson := DesignDocument{
+	if len(rows) != 2 || rows[1].ID != "b" {
+		t.Fatalf("unexpected rows %+v", rows)
+	}
+	var row struct {
+		Value struct {
+			N int `json:"n"`
+		} `json:"value"`
+	}
+	if err := s.Decode(&row); err != nil {
+		t.Fatal(err)
+	}
+	if row.Value.N != 2 {
+		t.Errorf("expected value 2 but got %d", row.Value.N)
+	}
+	// reduce results have no meta data and rows may come first
+	s = newViewStream(ioutil.NopCloser(strings.NewReader(`{"rows":[{"key":null,"value":42}],"update_seq":7}`)))
+	count := 0
+	for s.Next() {
+		count++
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || s.UpdateSeq.String() != "7" {
+		t.Errorf("expected 1 row and update seq 7 but got %d and %s", count, s.UpdateSeq)
+	}
+	// rows that do not fit Row can still be decoded into other types
+	s = newViewStream(ioutil.NopCloser(strings.NewReader(`{"rows":[{"id":1,"key":null}]}`)))
+	if !s.Next() {
+		t.Fatal(s.Err())
+	}
+	if _, err := s.Row(); err == nil {
+		t.Error("expected error for row with numeric id")
+	}
+	var numeric struct {
+		ID int `json:"id"`
+	}
+	if err := s.Decode(&numeric); err != nil || numeric.ID != 1 {
+		t.Errorf("expected id 1 but got %d and %v", numeric.ID, err)
+	}
+	// truncated response
+	s = newViewStream(ioutil.NopCloser(strings.NewReader(`{"total_rows":3,"rows":[{"id":"a"},`)))
+	for s.Next() {
+	}
+	if s.Err() == nil {
+		t.Error("expected error for truncated response")
+	}
+}
+
+func TestQueryParametersValidate(t *testing.T) {
+	tests := []struct {
+		desc   string
+		params *QueryParameters
+		valid  bool
+	}{
+		{"nil", nil, true},
+		{"empty", &QueryParameters{}, true},
+		{"group with default reduce", &QueryParameters{Group: pointer.Bool(true)}, true},
+		{"group without reduce", &QueryParameters{Group: pointer.Bool(true), Reduce: pointer.Bool(false)}, false},
+		{"group level without reduce", &QueryParameters{GroupLevel: pointer.Int(1), Reduce: pointer.Bool(false)}, false},
+		{"include docs with reduce", &QueryParameters{IncludeDocs: pointer.Bool(true), Reduce: pointer.Bool(true)}, false},
+		{"include docs without reduce", &QueryParameters{IncludeDocs: pointer.Bool(true), Reduce: pointer.Bool(false)}, true},
+		{"keys and key", &QueryParameters{Keys: []interface{}{"a"}, Key: "a"}, false},
+		{"keys and startkey", &QueryParameters{Keys: []interface{}{"a"}, StartKey: "a"}, false},
+		{"key and endkey", &QueryParameters{Key: "a", EndKey: "b"}, false},
+		{"startkey and endkey", &QueryParameters{StartKey: "a", EndKey: "b"}, true},
+		{"start_key and end_key", &QueryParameters{StartKeyAlias: "a", EndKeyAlias: "b"}, true},
+		{"startkey and start_key", &QueryParameters{StartKey: "a", StartKeyAlias: "a"}, false},
+		{"endkey and end_key", &QueryParameters{EndKey: "b", EndKeyAlias: "b"}, false},
+		{"startkey_docid and start_key_doc_id", &QueryParameters{StartKeyDocID: pointer.String("a"), StartKeyDocIDAlias: pointer.String("a")}, false},
+		{"endkey_docid and end_key_doc_id", &QueryParameters{EndKeyDocID: pointer.String("b"), EndKeyDocIDAlias: pointer.String("b")}, false},
+		{"keys and start_key", &QueryParameters{Keys: []interface{}{"a"}, StartKeyAlias: "a"}, false},
+		{"key and end_key", &QueryParameters{Key: "a", EndKeyAlias: "b"}, false},
+		{"stale", &QueryParameters{Stale: pointer.String("ok")}, true},
+		{"invalid stale", &QueryParameters{Stale: pointer.String("yes")}, false},
+		{"stale and stable", &QueryParameters{Stale: pointer.String("ok"), Stable: pointer.Bool(true)}, false},
+		{"update lazy", &QueryParameters{Update: pointer.String(ViewUpdateLazy), Stable: pointer.Bool(true)}, true},
+		{"invalid update", &QueryParameters{Update: pointer.String("later")}, false},
+		{"negative limit", &QueryParameters{Limit: pointer.Int(-1)}, false},
+		{"negative skip", &QueryParameters{Skip: pointer.Int(-1)}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			err := test.params.Validate()
+			if test.valid && err != nil {
+				t.Errorf("expected valid parameters but got %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Error("expected error but got none")
+			}
+		})
+	}
+	// invalid parameters are never sent
+	if _, err := (&QueryParameters{Limit: pointer.Int(-1)}).values(); err == nil {
+		t.Error("expected values to validate parameters")
+	}
+	q, err := (&QueryParameters{
+		EndKeyDocID: pointer.String("doc"),
+		Sorted:      pointer.Bool(false),
+		Update:      pointer.String(ViewUpdateFalse),
+	}).values()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Encode() != "endkey_docid=doc&sorted=false&update=false" {
+		t.Errorf("unexpected query %s", q.Encode())
+	}
+	q, err = (&QueryParameters{
+		StartKeyAlias:      []interface{}{"a", 1},
+		EndKeyAlias:        "b",
+		StartKeyDocIDAlias: pointer.String("doc"),
+	}).values()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Get("start_key") != `["a",1]` || q.Get("end_key") != `"b"` || q.Get("start_key_doc_id") != "doc" {
+		t.Errorf("unexpected query %s", q.Encode())
+	}
+	// paginators move startkey so the aliases are folded into it
+	params, err := pagingParams(QueryParameters{StartKeyAlias: "a", EndKeyDocIDAlias: pointer.String("doc")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.StartKey != "a" || params.StartKeyAlias != nil || *params.EndKeyDocID != "doc" || params.EndKeyDocIDAlias != nil {
+		t.Errorf("unexpected paging parameters %+v", params)
+	}
+}
+
+func TestViewPager(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	design := &DesignDocument{
 		Document: Document{
-			ID: "_design/person",
+			ID: "_design/pager",
 		},
 		Language: langJavaScript,
 		Views: map[string]DesignDocumentView{
-			"ageByGender": {
-				Map: `
-					function(doc) {
-						if (doc.type === 'person') {
-							emit(doc.gender, doc.age);
-						}
-					}
-				`,
-				Reduce: `
-					function(keys, values, rereduce) {
-						return sum(values);
-					}
-				`,
+			"byFoo": {
+				Map: `function(doc) { if (doc.type === 'data') { emit(doc.foo); } }`,
+			},
+		},
+	}
+	if _, err := db.Post(design); err != nil {
+		t.Fatal(err)
+	}
+	// 7 documents with key "b" between single documents with keys "a" and "c"
+	docs := []CouchDoc{
+		&DataDocument{Document: Document{ID: "a0"}, Type: "data", Foo: "a"},
+		&DataDocument{Document: Document{ID: "c0"}, Type: "data", Foo: "c"},
+	}
+	for i := 0; i < 7; i++ {
+		docs = append(docs, &DataDocument{Document: Document{ID: fmt.Sprintf("b%d", i)}, Type: "data", Foo: "b"})
+	}
+	if _, err := db.Bulk(docs); err != nil {
+		t.Fatal(err)
+	}
+	view := db.View("pager")
+	ids := func(rows []Row) string {
+		s := []string{}
+		for _, row := range rows {
+			s = append(s, row.ID)
+		}
+		return strings.Join(s, ",")
+	}
+	tests := []struct {
+		desc     string
+		params   QueryParameters
+		expected []string
+	}{
+		{
+			desc:     "all rows",
+			params:   QueryParameters{},
+			expected: []string{"a0,b0,b1", "b2,b3,b4", "b5,b6,c0"},
+		},
+		{
+			desc:     "all rows descending",
+			params:   QueryParameters{Descending: pointer.Bool(true)},
+			expected: []string{"c0,b6,b5", "b4,b3,b2", "b1,b0,a0"},
+		},
+		{
+			desc:     "duplicate key",
+			params:   QueryParameters{Key: "b"},
+			expected: []string{"b0,b1,b2", "b3,b4,b5", "b6"},
+		},
+		{
+			desc:     "duplicate key descending",
+			params:   QueryParameters{Key: "b", Descending: pointer.Bool(true)},
+			expected: []string{"b6,b5,b4", "b3,b2,b1", "b0"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			pager := NewViewPager(view, "byFoo", test.params, 3)
+			page, err := pager.Page("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.Prev != "" {
+				t.Error("expected first page without previous page")
+			}
+			pages := []*ViewPage{page}
+			for page.Next != "" {
+				if page, err = pager.Page(page.Next); err != nil {
+					t.Fatal(err)
+				}
+				pages = append(pages, page)
+			}
+			if len(pages) != len(test.expected) {
+				t.Fatalf("expected %d pages but got %d", len(test.expected), len(pages))
+			}
+			for i, p := range pages {
+				if ids(p.Rows) != test.expected[i] {
+					t.Errorf("expected page %d to be %s but got %s", i, test.expected[i], ids(p.Rows))
+				}
+			}
+			// walk back from the last page
+			for i := len(pages) - 2; i >= 0; i-- {
+				if page, err = pager.Page(page.Prev); err != nil {
+					t.Fatal(err)
+				}
+				if ids(page.Rows) != test.expected[i] {
+					t.Errorf("expected previous page %d to be %s but got %s", i, test.expected[i], ids(page.Rows))
+				}
+			}
+			if page.Prev != "" {
+				t.Error("expected first page without previous page")
+			}
+		})
+	}
+	t.Run("keys", func(t *testing.T) {
+		pager := NewViewPager(view, "byFoo", QueryParameters{Keys: []interface{}{"a"}}, 3)
+		if _, err := pager.Page(""); err != ErrPagingKeys {
+			t.Errorf("expected ErrPagingKeys but got %v", err)
+		}
+	})
+	t.Run("invalid token", func(t *testing.T) {
+		if _, err := NewViewPager(view, "byFoo", QueryParameters{}, 3).Page("garbage"); err != ErrInvalidPageToken {
+			t.Errorf("expected ErrInvalidPageToken but got %v", err)
+		}
+	})
+	t.Run("iterate all docs with key", func(t *testing.T) {
+		it := db.IterAllDocs(context.Background(), &QueryParameters{Key: "b3"}, 2)
+		count := 0
+		for it.Next() {
+			if it.Row().ID != "b3" {
+				t.Errorf("expected b3 but got %s", it.Row().ID)
+			}
+			count++
+		}
+		if err := it.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Errorf("expected 1 row but got %d", count)
+		}
+		it = db.IterAllDocs(context.Background(), &QueryParameters{Keys: []interface{}{"a0"}}, 2)
+		if it.Next() || it.Err() != ErrPagingKeys {
+			t.Errorf("expected ErrPagingKeys but got %v", it.Err())
+		}
+	})
+}
+
+func TestQueryParametersValues(t *testing.T) {
+	start, end := ArrayPrefixRange("user", 42)
+	tests := []struct {
+		params   *QueryParameters
+		expected map[string]string
+		json     string
+	}{
+		{
+			params: &QueryParameters{
+				Key:   []interface{}{"user", 42, HighKey()},
+				Limit: pointer.Int(10),
+			},
+			expected: map[string]string{
+				"key":   `["user",42,{}]`,
+				"limit": "10",
+			},
+			json: `{"limit":10,"key":["user",42,{}]}`,
+		},
+		{
+			params: &QueryParameters{
+				Keys: []interface{}{"a", 1},
+			},
+			expected: map[string]string{
+				"keys": `["a",1]`,
+			},
+			json: `{"keys":["a",1]}`,
+		},
+		{
+			params: &QueryParameters{
+				StartKey: start,
+				EndKey:   end,
+			},
+			expected: map[string]string{
+				"startkey": `["user",42]`,
+				"endkey":   `["user",42,{}]`,
+			},
+			json: `{"endkey":["user",42,{}],"startkey":["user",42]}`,
+		},
+	}
+	for _, test := range tests {
+		q, err := test.params.values()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, value := range test.expected {
+			if q.Get(name) != value {
+				t.Errorf("expected %s=%s but got %s", name, value, q.Get(name))
+			}
+		}
+		// multi query bodies use the same names
+		b, err := json.Marshal(test.params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != test.json {
+			t.Errorf("expected JSON %s but got %s", test.json, b)
+		}
+	}
+	// unset keys are not sent
+	q, err := (&QueryParameters{}).values()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q) != 0 {
+		t.Errorf("expected empty values but got %v", q)
+	}
+	// changing a high key does not change other ranges
+	_, end3 := ArrayPrefixRange("user")
+	end3[1].(map[string]interface{})["x"] = 1
+	if _, end4 := ArrayPrefixRange("user"); !reflect.DeepEqual(end4[1], HighKey()) {
+		t.Errorf("expected empty high key but got %v", end4[1])
+	}
+	start2, end2 := PrefixRange("abc")
+	if start2 != "abc" || end2 != "abc\ufff0" {
+		t.Errorf("unexpected prefix range %q %q", start2, end2)
+	}
+}
+
+func TestShowList(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	design := &DesignDocument{
+		Document: Document{
+			ID: "_design/render",
+		},
+		Language: langJavaScript,
+		Views: map[string]DesignDocumentView{
+			"byName": {
+				Map: `function(doc) { if (doc.name) { emit(doc.name, doc.age); } }`,
 			},
 		},
+		Shows: map[string]string{
+			"html": `function(doc, req) {
+				return '<h1>' + (doc ? doc.name : req.query.fallback) + '</h1>';
+			}`,
+			"json": `function(doc, req) {
+				return {json: {name: doc.name, age: doc.age}};
+			}`,
+		},
+		Lists: map[string]string{
+			"csv": `function(head, req) {
+				start({headers: {'Content-Type': 'text/csv'}});
+				var row;
+				while (row = getRow()) {
+					send(row.key + req.query.separator + row.value + '\n');
+				}
+			}`,
+		},
 	}
-	if _, err := db.Post(&designPerson); err != nil {
+	if _, err := db.Post(design); err != nil {
 		t.Fatal(err)
 	}
-	// create dummy data
-	doc1 := &DataDocument{
-		Type: "data",
-		Foo:  "foo1",
-		Beep: "beep1",
-		Age:  10,
+	docs := []CouchDoc{
+		&Person{Document: Document{ID: "alice"}, Name: "Alice", Age: 31},
+		&Person{Document: Document{ID: "bob"}, Name: "Bob", Age: 25},
 	}
-	if _, err := db.Post(doc1); err != nil {
+	if _, err := db.Bulk(docs); err != nil {
 		t.Fatal(err)
 	}
-	doc2 := &DataDocument{
-		Type: "data",
-		Foo:  "foo2",
-		Beep: "beep2",
-		Age:  20,
+	view := db.View("render")
+	t.Run("show", func(t *testing.T) {
+		res, err := view.Show("html", "alice", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != "<h1>Alice</h1>" {
+			t.Errorf("expected <h1>Alice</h1> but got %s", body)
+		}
+		if !strings.HasPrefix(res.ContentType, "text/html") {
+			t.Errorf("expected html content type but got %s", res.ContentType)
+		}
+	})
+	t.Run("show without document", func(t *testing.T) {
+		res, err := view.Show("html", "", url.Values{"fallback": {"nobody"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != "<h1>nobody</h1>" {
+			t.Errorf("expected <h1>nobody</h1> but got %s", body)
+		}
+	})
+	t.Run("show json", func(t *testing.T) {
+		res, err := view.Show("json", "bob", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var p Person
+		if err := res.Decode(&p); err != nil {
+			t.Fatal(err)
+		}
+		if p.Name != "Bob" || p.Age != 25 {
+			t.Errorf("unexpected person %+v", p)
+		}
+	})
+	t.Run("list", func(t *testing.T) {
+		params := QueryParameters{
+			Limit: pointer.Int(1),
+		}
+		res, err := view.List("csv", "byName", params, url.Values{"separator": {";"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != "Alice;31\n" {
+			t.Errorf("expected Alice;31 but got %q", body)
+		}
+		if !strings.HasPrefix(res.ContentType, "text/csv") {
+			t.Errorf("expected csv content type but got %s", res.ContentType)
+		}
+	})
+}
+
+func TestListPath(t *testing.T) {
+	paths := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath()+"?"+r.URL.RawQuery)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
 	}
-	if _, err := db.Post(doc2); err != nil {
+	c, err := NewClient(u)
+	if err != nil {
 		t.Fatal(err)
 	}
-	// create multiple persons
-	data := []struct {
-		Name   string
-		Age    float64
-		Gender string
+	view := c.Use("db").View("render")
+	tests := []struct {
+		view string
+		path string
 	}{
-		{"John", 45, "male"},
-		{"Frank", 40, "male"},
-		{"Steve", 60, "male"},
-		{"Max", 26, "male"},
-		{"Marc", 36, "male"},
-		{"Nick", 18, "male"},
-		{"Jessica", 49, "female"},
-		{"Lily", 20, "female"},
-		{"Sophia", 66, "female"},
-		{"Chloe", 12, "female"},
+		{"byName", "/db/_design/render/_list/csv/byName?limit=1"},
+		{"by name?#%", "/db/_design/render/_list/csv/by%20name%3F%23%25?limit=1"},
+		{"other/by name", "/db/_design/render/_list/csv/other/by%20name?limit=1"},
 	}
-	people := make([]CouchDoc, len(data))
-	for index, d := range data {
-		people[index] = &Person{
-			Type:   "person",
-			Name:   d.Name,
-			Age:    d.Age,
-			Gender: d.Gender,
+	for _, tt := range tests {
+		res, err := view.List("csv", tt.view, QueryParameters{Limit: pointer.Int(1)}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Close()
+		if paths[len(paths)-1] != tt.path {
+			t.Errorf("view %q: expected path %s but got %s", tt.view, tt.path, paths[len(paths)-1])
 		}
 	}
-	// bulk save people to database
-	if _, err := db.Bulk(people); err != nil {
+}
+
+func TestUpdate(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	design := &DesignDocument{
+		Document: Document{
+			ID: "_design/counter",
+		},
+		Language: langJavaScript,
+		Updates: map[string]string{
+			"increment": `function(doc, req) {
+				if (!doc) {
+					if (!req.id) {
+						return [null, {code: 400, body: 'missing id'}];
+					}
+					doc = {_id: req.id, count: 0};
+				}
+				var by = parseInt(req.form.by || '1', 10);
+				if (by < 0) {
+					throw({forbidden: 'negative increment'});
+				}
+				doc.count += by;
+				return [doc, {json: {count: doc.count}}];
+			}`,
+			"echo": `function(doc, req) {
+				return [null, req.body];
+			}`,
+		},
+	}
+	if _, err := db.Post(design); err != nil {
 		t.Fatal(err)
 	}
-
-	// run sub test
-	t.Run("get", func(t *testing.T) {
-		view := db.View("test")
-		params := QueryParameters{}
-		res, err := view.Get("foo", params)
+	view := db.View("counter")
+	t.Run("create and increment", func(t *testing.T) {
+		res, err := view.UpdateForm("increment", "visits", url.Values{"by": {"2"}})
 		if err != nil {
 			t.Fatal(err)
 		}
-		if res.TotalRows != 2 {
-			t.Errorf("expected total rows to be 2 but got %d", res.TotalRows)
+		if res.ID != "visits" || !strings.HasPrefix(res.NewRev, "1-") {
+			t.Errorf("expected new document visits but got %+v", res)
 		}
-		if res.Offset != 0 {
-			t.Errorf("expected offset to be 0 but got %d", res.Offset)
+		res, err = view.UpdateForm("increment", "visits", url.Values{"by": {"3"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(res.NewRev, "2-") {
+			t.Errorf("expected second revision but got %s", res.NewRev)
+		}
+		var counter struct {
+			Count int `json:"count"`
+		}
+		if err := res.Decode(&counter); err != nil {
+			t.Fatal(err)
+		}
+		if counter.Count != 5 {
+			t.Errorf("expected count 5 but got %d", counter.Count)
 		}
 	})
-
-	t.Run("design document name", func(t *testing.T) {
-		doc := new(DesignDocument)
-		err := db.Get(doc, "_design/test")
+	t.Run("raw body", func(t *testing.T) {
+		res, err := view.Update("echo", "", strings.NewReader("hello"), "text/plain")
 		if err != nil {
 			t.Fatal(err)
 		}
-		if doc.Name() != "test" {
-			t.Errorf("expected name to be test but got %s", doc.Name())
+		if string(res.Body) != "hello" || res.NewRev != "" {
+			t.Errorf("unexpected response %+v", res)
 		}
 	})
+	t.Run("thrown error", func(t *testing.T) {
+		_, err := view.UpdateForm("increment", "visits", url.Values{"by": {"-1"}})
+		couchErr, ok := err.(*Error)
+		if !ok {
+			t.Fatalf("expected *Error but got %v", err)
+		}
+		if couchErr.StatusCode != http.StatusForbidden || couchErr.Reason != "negative increment" {
+			t.Errorf("unexpected error %+v", couchErr)
+		}
+	})
+	t.Run("error response", func(t *testing.T) {
+		_, err := view.UpdateForm("increment", "", nil)
+		couchErr, ok := err.(*Error)
+		if !ok {
+			t.Fatalf("expected *Error but got %v", err)
+		}
+		if couchErr.StatusCode != http.StatusBadRequest || couchErr.Reason != "missing id" {
+			t.Errorf("unexpected error %+v", couchErr)
+		}
+	})
+}
 
-	t.Run("design document view", func(t *testing.T) {
-		doc := new(DesignDocument)
-		err := db.Get(doc, "_design/test")
+func TestNewError(t *testing.T) {
+	tests := []struct {
+		body   string
+		typ    string
+		reason string
+	}{
+		{`{"error":"not_found","reason":"missing"}`, "not_found", "missing"},
+		{"missing id\n", "", "missing id"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(http.MethodPut, "http://127.0.0.1:5984/db/doc", nil)
 		if err != nil {
 			t.Fatal(err)
 		}
-		if _, ok := doc.Views["foo"]; !ok {
-			t.Error("expected foo mapping function to exists but it does not")
+		res := &http.Response{
+			StatusCode: http.StatusBadRequest,
+			Body:       ioutil.NopCloser(strings.NewReader(tt.body)),
+			Request:    req,
 		}
-	})
+		err = newError(res)
+		couchErr, ok := err.(*Error)
+		if !ok {
+			t.Fatalf("expected *Error but got %v", err)
+		}
+		if couchErr.Type != tt.typ || couchErr.Reason != tt.reason || couchErr.StatusCode != http.StatusBadRequest || couchErr.Method != http.MethodPut {
+			t.Errorf("body %q: unexpected error %+v", tt.body, couchErr)
+		}
+	}
+}
 
-	t.Run("get with query parameters", func(t *testing.T) {
-		view := db.View("test")
-		params := QueryParameters{
-			Key: pointer.String(fmt.Sprintf("%q", "foo1")),
+func TestReduce(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	design := &DesignDocument{
+		Document: Document{
+			ID: "_design/orders",
+		},
+		Language: langJavaScript,
+		Views: map[string]DesignDocumentView{
+			"count": {
+				Map:    `function(doc) { emit([doc.year, doc.month], null); }`,
+				Reduce: "_count",
+			},
+			"sum": {
+				Map:    `function(doc) { emit([doc.year, doc.month], [doc.total, 1]); }`,
+				Reduce: "_sum",
+			},
+			"stats": {
+				Map:    `function(doc) { emit([doc.year, doc.month], doc.total); }`,
+				Reduce: "_stats",
+			},
+			"customers": {
+				Map:    `function(doc) { emit(doc.customer, null); }`,
+				Reduce: "_approx_count_distinct",
+			},
+		},
+	}
+	if _, err := db.Post(design); err != nil {
+		t.Fatal(err)
+	}
+	type order struct {
+		Document
+		Year     int     `json:"year"`
+		Month    int     `json:"month"`
+		Total    float64 `json:"total"`
+		Customer string  `json:"customer"`
+	}
+	docs := []CouchDoc{
+		&order{Year: 2017, Month: 1, Total: 10, Customer: "a"},
+		&order{Year: 2017, Month: 1, Total: 20, Customer: "b"},
+		&order{Year: 2017, Month: 2, Total: 30, Customer: "a"},
+		&order{Year: 2018, Month: 1, Total: 40, Customer: "c"},
+	}
+	if _, err := db.Bulk(docs); err != nil {
+		t.Fatal(err)
+	}
+	view := db.View("orders")
+	t.Run("count", func(t *testing.T) {
+		rows, err := view.Reduce("count", GroupNone, QueryParameters{})
+		if err != nil {
+			t.Fatal(err)
 		}
-		res, err := view.Get("foo", params)
+		if len(rows) != 1 {
+			t.Fatalf("expected 1 row but got %d", len(rows))
+		}
+		count, err := rows[0].Count()
 		if err != nil {
 			t.Fatal(err)
 		}
-		if len(res.Rows) != 1 {
-			t.Errorf("expected only one row but got %d", len(res.Rows))
+		if count != 4 {
+			t.Errorf("expected count 4 but got %d", count)
 		}
 	})
-
-	t.Run("get with start and end key", func(t *testing.T) {
-		view := db.View("test")
-		params := QueryParameters{
-			StartKey: pointer.String(fmt.Sprintf("[%q,%q]", "foo2", "beep2")),
-			EndKey:   pointer.String(fmt.Sprintf("[%q,%q]", "foo2", "beep2")),
-		}
-		res, err := view.Get("complex", params)
+	t.Run("sum by year", func(t *testing.T) {
+		rows, err := view.Reduce("sum", 1, QueryParameters{})
 		if err != nil {
 			t.Fatal(err)
 		}
-		if len(res.Rows) != 1 {
-			t.Errorf("expected only one row but got %d", len(res.Rows))
+		if len(rows) != 2 {
+			t.Fatalf("expected 2 rows but got %d", len(rows))
 		}
-	})
-
-	t.Run("get with integer", func(t *testing.T) {
-		view := db.View("test")
-		params := QueryParameters{
-			StartKey: pointer.String(fmt.Sprintf("[%q,%d]", "foo2", 20)),
-			EndKey:   pointer.String(fmt.Sprintf("[%q,%d]", "foo2", 20)),
+		var year []int
+		if err := rows[0].DecodeKey(&year); err != nil {
+			t.Fatal(err)
 		}
-		res, err := view.Get("int", params)
+		sums, err := rows[0].Sums()
 		if err != nil {
 			t.Fatal(err)
 		}
-		if len(res.Rows) != 1 {
-			t.Errorf("expected only one row but got %d", len(res.Rows))
+		if year[0] != 2017 || sums[0] != 60 || sums[1] != 3 {
+			t.Errorf("expected 2017 with total 60 of 3 orders but got %v %v", year, sums)
 		}
 	})
-
-	t.Run("get with reduce", func(t *testing.T) {
-		view := db.View("person")
-		params := QueryParameters{}
-		res, err := view.Get("ageByGender", params)
-		if err != nil {
+	t.Run("stats by month", func(t *testing.T) {
+		rows := []struct {
+			Key   []int `json:"key"`
+			Value Stats `json:"value"`
+		}{}
+		if err := view.ReduceRows("stats", GroupExact, QueryParameters{}, &rows); err != nil {
 			t.Fatal(err)
 		}
-		ageTotalSum := res.Rows[0].Value.(float64)
-		if ageTotalSum != 372 {
-			t.Fatalf("expected age 372 but got %v", ageTotalSum)
+		if len(rows) != 3 {
+			t.Fatalf("expected 3 rows but got %d", len(rows))
+		}
+		expected := Stats{Sum: 30, Count: 2, Min: 10, Max: 20, Sumsqr: 500}
+		if rows[0].Key[1] != 1 || rows[0].Value != expected {
+			t.Errorf("expected %+v but got %v %+v", expected, rows[0].Key, rows[0].Value)
 		}
 	})
-
-	t.Run("get with reduce and group", func(t *testing.T) {
-		view := db.View("person")
-		params := QueryParameters{
-			Key:        pointer.String(fmt.Sprintf("%q", "female")),
-			GroupLevel: pointer.Int(1),
+	t.Run("approx count distinct", func(t *testing.T) {
+		rows, err := view.Reduce("customers", GroupNone, QueryParameters{})
+		if err != nil {
+			t.Fatal(err)
 		}
-		res, err := view.Get("ageByGender", params)
+		count, err := rows[0].ApproxCountDistinct()
 		if err != nil {
 			t.Fatal(err)
 		}
-		ageTotalFemale := res.Rows[0].Value.(float64)
-		if ageTotalFemale != 147 {
-			t.Fatalf("expected age 147 but got %v", ageTotalFemale)
+		if count != 3 {
+			t.Errorf("expected 3 customers but got %d", count)
 		}
 	})
+}
 
-	t.Run("get without reduce", func(t *testing.T) {
-		view := db.View("person")
-		params := QueryParameters{
-			Key:    pointer.String(fmt.Sprintf("%q", "male")),
-			Reduce: pointer.Bool(false),
-		}
-		res, err := view.Get("ageByGender", params)
+func TestReduceRow(t *testing.T) {
+	row := ReduceRow{
+		Key:   json.RawMessage(`["2017",1]`),
+		Value: json.RawMessage(`{"sum":30,"count":2,"min":10,"max":20,"sumsqr":500}`),
+	}
+	stats, err := row.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Count != 2 || stats.Sum != 30 || stats.Sumsqr != 500 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if _, err := row.Sum(); err == nil {
+		t.Error("expected error decoding stats as sum")
+	}
+	row.Value = json.RawMessage(`[60,3]`)
+	sums, err := row.Sums()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sums, []float64{60, 3}) {
+		t.Errorf("unexpected sums %v", sums)
+	}
+	row.Value = json.RawMessage(`42`)
+	if count, err := row.Count(); err != nil || count != 42 {
+		t.Errorf("expected count 42 but got %d %v", count, err)
+	}
+	if count, err := row.ApproxCountDistinct(); err != nil || count != 42 {
+		t.Errorf("expected approximate count 42 but got %d %v", count, err)
+	}
+	// group levels
+	tests := []struct {
+		level      int
+		group      *bool
+		groupLevel *int
+	}{
+		{GroupNone, nil, nil},
+		{GroupExact, pointer.Bool(true), nil},
+		{2, nil, pointer.Int(2)},
+	}
+	for _, tt := range tests {
+		params, err := reduceParams(QueryParameters{Group: pointer.Bool(true), Reduce: pointer.Bool(false)}, tt.level)
 		if err != nil {
-			t.Fatal(err)
-		}
-		if len(res.Rows) != 6 {
-			t.Fatalf("expected 6 rows but got %d instead", len(res.Rows))
+			t.Fatalf("level %d: %v", tt.level, err)
 		}
-	})
-
-	t.Run("post", func(t *testing.T) {
-		view := db.View("person")
-		params := QueryParameters{
-			Reduce: pointer.Bool(false),
+		if params.Reduce == nil || !*params.Reduce {
+			t.Errorf("level %d: expected reduce", tt.level)
 		}
-		res, err := view.Post("ageByGender", []string{"male"}, params)
-		if err != nil {
-			t.Fatal(err)
+		if !reflect.DeepEqual(params.Group, tt.group) || !reflect.DeepEqual(params.GroupLevel, tt.groupLevel) {
+			t.Errorf("level %d: unexpected group %v and group level %v", tt.level, params.Group, params.GroupLevel)
 		}
-		if len(res.Rows) != 6 {
-			t.Fatalf("expected 6 rows but got %d instead", len(res.Rows))
+		if err := params.Validate(); err != nil {
+			t.Errorf("level %d: %v", tt.level, err)
 		}
-	})
-
+	}
+	// only GroupExact may be negative
+	if _, err := reduceParams(QueryParameters{}, -2); err == nil {
+		t.Error("expected error for group level -2")
+	}
 }
 
 // mimeType()
@@ -1646,6 +3975,158 @@ func TestDiff(t *testing.T) {
 			changes:   0,
 			deletions: 0,
 		},
+		{
+			desc: "database has an old filter function",
+			cache: []DesignDocument{
+				{
+					Document: Document{
+						ID: "_design/player",
+					},
+					Filters: map[string]string{
+						"filter": "function() { /* new */ }",
+					},
+				},
+			},
+			database: []DesignDocument{
+				{
+					Document: Document{
+						ID:  "_design/player",
+						Rev: "abc",
+					},
+					Filters: map[string]string{
+						"filter": "function() { /* old */ }",
+					},
+				},
+			},
+			additions: 0,
+			changes:   1,
+			deletions: 0,
+		},
+		{
+			desc: "database has an old show function",
+			cache: []DesignDocument{
+				{
+					Document: Document{
+						ID: "_design/player",
+					},
+					Shows: map[string]string{
+						"show": "function() { /* new */ }",
+					},
+				},
+			},
+			database: []DesignDocument{
+				{
+					Document: Document{
+						ID:  "_design/player",
+						Rev: "abc",
+					},
+					Shows: map[string]string{
+						"show": "function() { /* old */ }",
+					},
+				},
+			},
+			additions: 0,
+			changes:   1,
+			deletions: 0,
+		},
+		{
+			desc: "database has an old list function",
+			cache: []DesignDocument{
+				{
+					Document: Document{
+						ID: "_design/player",
+					},
+					Lists: map[string]string{
+						"list": "function() { /* new */ }",
+					},
+				},
+			},
+			database: []DesignDocument{
+				{
+					Document: Document{
+						ID:  "_design/player",
+						Rev: "abc",
+					},
+					Lists: map[string]string{
+						"list": "function() { /* old */ }",
+					},
+				},
+			},
+			additions: 0,
+			changes:   1,
+			deletions: 0,
+		},
+		{
+			desc: "database has an old update function",
+			cache: []DesignDocument{
+				{
+					Document: Document{
+						ID: "_design/player",
+					},
+					Updates: map[string]string{
+						"update": "function() { /* new */ }",
+					},
+				},
+			},
+			database: []DesignDocument{
+				{
+					Document: Document{
+						ID:  "_design/player",
+						Rev: "abc",
+					},
+					Updates: map[string]string{
+						"update": "function() { /* old */ }",
+					},
+				},
+			},
+			additions: 0,
+			changes:   1,
+			deletions: 0,
+		},
+		{
+			desc: "database has the same functions",
+			cache: []DesignDocument{
+				{
+					Document: Document{
+						ID: "_design/player",
+					},
+					Shows: map[string]string{
+						"show": "function() {}",
+					},
+					Lists: map[string]string{},
+				},
+			},
+			database: []DesignDocument{
+				{
+					Document: Document{
+						ID:  "_design/player",
+						Rev: "abc",
+					},
+					Shows: map[string]string{
+						"show": "function() {}",
+					},
+				},
+			},
+			additions: 0,
+			changes:   0,
+			deletions: 0,
+		},
+		{
+			desc:  "database has mango index design document which should not be deleted",
+			cache: []DesignDocument{},
+			database: []DesignDocument{
+				{
+					Document: Document{
+						ID:  "_design/people",
+						Rev: "abc",
+					},
+					Language: langQuery,
+				},
+			},
+			additions: 0,
+			changes:   0,
+			deletions: 0,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.desc, func(t *testing.T) {
@@ -1675,6 +4156,105 @@ func TestDiff(t *testing.T) {
 	}
 }
 
+func TestDiffIndexes(t *testing.T) {
+	existing := []IndexInfo{
+		{
+			Name: "_all_docs",
+			Type: IndexTypeSpecial,
+			Def: Index{
+				Fields: []map[string]string{Asc("_id")},
+			},
+		},
+		{
+			DDoc: "_design/people",
+			Name: "by-age",
+			Type: IndexTypeJSON,
+			Def: Index{
+				Fields:                []map[string]string{Asc("age")},
+				PartialFilterSelector: map[string]interface{}{"type": map[string]interface{}{"$eq": "person"}},
+			},
+		},
+		{
+			DDoc: "_design/people",
+			Name: "unmanaged",
+			Type: IndexTypeJSON,
+			Def: Index{
+				Fields: []map[string]string{Asc("gender")},
+			},
+		},
+	}
+	byAge := IndexDefinition{
+		Index: Index{
+			Fields:                []map[string]string{Asc("age")},
+			PartialFilterSelector: Eq("type", "person"),
+		},
+		DDoc: "people",
+		Name: "by-age",
+	}
+	tests := []struct {
+		desc    string
+		desired func(IndexDefinition) IndexDefinition
+		prune   bool
+		create  int
+		replace int
+		delete  int
+	}{
+		{
+			desc:    "unchanged",
+			desired: func(i IndexDefinition) IndexDefinition { return i },
+		},
+		{
+			desc:    "unchanged with prune",
+			desired: func(i IndexDefinition) IndexDefinition { return i },
+			prune:   true,
+			delete:  1,
+		},
+		{
+			desc: "new name",
+			desired: func(i IndexDefinition) IndexDefinition {
+				i.Name = "by-age-v2"
+				return i
+			},
+			create: 1,
+		},
+		{
+			desc: "different fields",
+			desired: func(i IndexDefinition) IndexDefinition {
+				i.Index.Fields = []map[string]string{Desc("age")}
+				return i
+			},
+			replace: 1,
+		},
+		{
+			desc: "different partial filter",
+			desired: func(i IndexDefinition) IndexDefinition {
+				i.Index.PartialFilterSelector = nil
+				return i
+			},
+			replace: 1,
+		},
+		{
+			desc: "different type",
+			desired: func(i IndexDefinition) IndexDefinition {
+				i.Type = IndexTypeText
+				return i
+			},
+			replace: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			plan, err := diffIndexes([]IndexDefinition{test.desired(byAge)}, existing, test.prune)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(plan.Create) != test.create || len(plan.Replace) != test.replace || len(plan.Delete) != test.delete {
+				t.Errorf("exp %d/%d/%d create/replace/delete but got %+v", test.create, test.replace, test.delete, plan)
+			}
+		})
+	}
+}
+
 // remove all white space and line breaks from string
 func clean(s string) string {
 	return strings.Replace(strings.Replace(s, " ", "", -1), "\n", "", -1)
@@ -1852,3 +4432,121 @@ func TestSeed(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestSeedWithIndexes(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	index := IndexDefinition{
+		Index: Index{
+			Fields: []map[string]string{Asc("age")},
+		},
+		DDoc: "people",
+		Name: "by-age",
+	}
+	if _, err := db.SeedIndexes([]IndexDefinition{index}, SeedIndexesOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	docs, err := client.Parse(filepath.Join("example", "design"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// seeding design documents neither fails on nor removes the index
+	if err := db.Seed(docs); err != nil {
+		t.Fatal(err)
+	}
+	designDocs, err := db.AllDesignDocs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(designDocs) != len(docs) {
+		t.Errorf("expected %d design documents but got %d", len(docs), len(designDocs))
+	}
+	list, err := db.ListIndexes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Indexes) != 2 || list.Indexes[1].Name != "by-age" {
+		t.Errorf("expected index by-age to survive seeding but got %+v", list.Indexes)
+	}
+}
+
+func TestSeedIndexes(t *testing.T) {
+	name, err := RandDBName(10)
+	if err != nil {
+		t.Error(err)
+	}
+	// create database
+	if _, err := client.Create(name); err != nil {
+		t.Error(err)
+	}
+	defer client.Delete(name)
+	db := client.Use(name)
+	byAge := IndexDefinition{
+		Index: Index{
+			Fields: []map[string]string{Asc("age")},
+		},
+		DDoc: "people",
+		Name: "by-age",
+	}
+	byName := IndexDefinition{
+		Index: Index{
+			Fields:                []map[string]string{Asc("name")},
+			PartialFilterSelector: Eq("type", "person"),
+		},
+		DDoc: "people",
+		Name: "by-name",
+	}
+	plan, err := db.SeedIndexes([]IndexDefinition{byAge, byName}, SeedIndexesOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Create) != 2 {
+		t.Errorf("expected 2 indexes to create but got %+v", plan)
+	}
+	// seeding again does nothing
+	plan, err = db.SeedIndexes([]IndexDefinition{byAge, byName}, SeedIndexesOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Create) != 0 || len(plan.Replace) != 0 || len(plan.Delete) != 0 {
+		t.Errorf("expected empty plan but got %+v", plan)
+	}
+	// change by-age and drop by-name
+	byAge.Index.Fields = []map[string]string{Asc("age"), Asc("name")}
+	plan, err = db.SeedIndexes([]IndexDefinition{byAge}, SeedIndexesOptions{DryRun: true, Prune: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Replace) != 1 || len(plan.Delete) != 1 || plan.Delete[0].Name != "by-name" {
+		t.Errorf("expected to replace by-age and delete by-name but got %+v", plan)
+	}
+	// dry run does not change anything
+	list, err := db.ListIndexes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Indexes) != 3 {
+		t.Fatalf("expected 3 indexes but got %d", len(list.Indexes))
+	}
+	if _, err := db.SeedIndexes([]IndexDefinition{byAge}, SeedIndexesOptions{Prune: true}); err != nil {
+		t.Fatal(err)
+	}
+	list, err = db.ListIndexes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Indexes) != 2 {
+		t.Fatalf("expected 2 indexes but got %+v", list.Indexes)
+	}
+	if !reflect.DeepEqual(list.Indexes[1].Def.Fields, byAge.Index.Fields) {
+		t.Errorf("expected fields %v but got %v", byAge.Index.Fields, list.Indexes[1].Def.Fields)
+	}
+}
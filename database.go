@@ -2,6 +2,7 @@ package couchdb
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"mime/multipart"
@@ -10,13 +11,14 @@ import (
 	"os"
 	"reflect"
 	"strings"
-
-	"github.com/google/go-querystring/query"
 )
 
 // DatabaseService is an interface for dealing with a single CouchDB database.
 type DatabaseService interface {
 	AllDocs(params *QueryParameters) (*ViewResponse, error)
+	AllDocsKeys(keys []string, params *QueryParameters) (*ViewResponse, error)
+	AllDocsQueries(queries []QueryParameters) ([]ViewResponse, error)
+	IterAllDocs(ctx context.Context, params *QueryParameters, pageSize int) *AllDocsIterator
 	AllDesignDocs() ([]DesignDocument, error)
 	Head(id string) (*http.Response, error)
 	Get(doc CouchDoc, id string) error
@@ -25,10 +27,26 @@ type DatabaseService interface {
 	Delete(doc CouchDoc) (*DocumentResponse, error)
 	PutAttachment(doc CouchDoc, path string) (*DocumentResponse, error)
 	Bulk(docs []CouchDoc) ([]DocumentResponse, error)
+	DeleteWhereIDs(ids []string, options DeleteOptions) (*DeleteResult, error)
+	DeleteWhereRange(startKey, endKey string, options DeleteOptions) (*DeleteResult, error)
+	DeleteWhereSelector(selector interface{}, options DeleteOptions) (*DeleteResult, error)
 	Purge(req map[string][]string) (*PurgeResponse, error)
+	RevsDiff(req map[string][]string) (map[string]RevsDiff, error)
+	MissingRevs(req map[string][]string) (*MissingRevsResponse, error)
+	Changes(params *ChangesParameters) (*ChangesResponse, error)
+	StreamChanges(ctx context.Context, params *ChangesParameters, options StreamOptions) *ChangesStream
+	Find(query FindQuery, docs interface{}) (*FindResponse, error)
+	IterFind(ctx context.Context, query FindQuery, pageSize int) *FindIterator
+	Explain(query FindQuery) (*ExplainResponse, error)
+	CreateIndex(index IndexDefinition) (*CreateIndexResponse, error)
+	ListIndexes() (*IndexList, error)
+	DeleteIndex(ddoc, typ, name string) (*DatabaseResponse, error)
+	SeedIndexes(indexes []IndexDefinition, options SeedIndexesOptions) (*IndexPlan, error)
 	GetSecurity() (*SecurityDocument, error)
 	PutSecurity(secDoc SecurityDocument) (*DatabaseResponse, error)
 	View(name string) ViewService
+	CompactView(ddoc string) (*DatabaseResponse, error)
+	ViewCleanup() (*DatabaseResponse, error)
 	Seed([]DesignDocument) error
 }
 
@@ -39,23 +57,26 @@ type Database struct {
 }
 
 // AllDesignDocs returns all design documents from database.
+// Design documents holding Mango indexes are not returned.
 // http://stackoverflow.com/questions/2814352/get-all-design-documents-in-couchdb
 func (db *Database) AllDesignDocs() ([]DesignDocument, error) {
-	startKey := fmt.Sprintf("%q", "_design/")
-	endKey := fmt.Sprintf("%q", "_design0")
 	includeDocs := true
 	q := QueryParameters{
-		StartKey:    &startKey,
-		EndKey:      &endKey,
+		StartKey:    "_design/",
+		EndKey:      "_design0",
 		IncludeDocs: &includeDocs,
 	}
 	res, err := db.AllDocs(&q)
 	if err != nil {
 		return nil, err
 	}
-	docs := make([]interface{}, len(res.Rows))
-	for index, row := range res.Rows {
-		docs[index] = row.Doc
+	docs := []interface{}{}
+	for _, row := range res.Rows {
+		// Mango indexes have no map functions and are managed by SeedIndexes
+		if language, _ := row.Doc["language"].(string); language == langQuery {
+			continue
+		}
+		docs = append(docs, row.Doc)
 	}
 	designDocs := make([]DesignDocument, len(docs))
 	b, err := json.Marshal(docs)
@@ -68,12 +89,44 @@ func (db *Database) AllDesignDocs() ([]DesignDocument, error) {
 // AllDocs returns all documents in selected database.
 // http://docs.couchdb.org/en/latest/api/database/bulk-api.html
 func (db *Database) AllDocs(params *QueryParameters) (*ViewResponse, error) {
-	q, err := query.Values(params)
+	return db.allDocs(context.Background(), params)
+}
+
+func (db *Database) allDocs(ctx context.Context, params *QueryParameters) (*ViewResponse, error) {
+	q, err := params.values()
+	if err != nil {
+		return nil, err
+	}
+	u := fmt.Sprintf("%s/_all_docs?%s", url.PathEscape(db.Name), q.Encode())
+	res, err := db.Client.RequestContext(ctx, http.MethodGet, u, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var response ViewResponse
+	return &response, json.NewDecoder(res.Body).Decode(&response)
+}
+
+// AllDocsKeys returns the documents for the given keys.
+// Keys that do not exist in the database are returned as rows
+// with Error set to "not_found".
+// http://docs.couchdb.org/en/latest/api/database/bulk-api.html#post--db-_all_docs
+func (db *Database) AllDocsKeys(keys []string, params *QueryParameters) (*ViewResponse, error) {
+	content := struct {
+		Keys []string `json:"keys"`
+	}{
+		Keys: keys,
+	}
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(content); err != nil {
+		return nil, err
+	}
+	q, err := params.values()
 	if err != nil {
 		return nil, err
 	}
 	u := fmt.Sprintf("%s/_all_docs?%s", url.PathEscape(db.Name), q.Encode())
-	res, err := db.Client.Request(http.MethodGet, u, nil, "")
+	res, err := db.Client.Request(http.MethodPost, u, &b, "application/json")
 	if err != nil {
 		return nil, err
 	}
@@ -82,9 +135,34 @@ func (db *Database) AllDocs(params *QueryParameters) (*ViewResponse, error) {
 	return &response, json.NewDecoder(res.Body).Decode(&response)
 }
 
+// AllDocsQueries executes multiple queries against _all_docs in a single request.
+// It returns one result per query in the same order. Requires CouchDB 2.2 or later.
+// http://docs.couchdb.org/en/latest/api/database/bulk-api.html#post--db-_all_docs-queries
+func (db *Database) AllDocsQueries(queries []QueryParameters) ([]ViewResponse, error) {
+	return multiQuery(db.Client, fmt.Sprintf("%s/_all_docs/queries", url.PathEscape(db.Name)), queries)
+}
+
+// IterAllDocs returns an iterator over all documents in selected database.
+// Rows are fetched lazily in pages of pageSize rows.
+// params.Keys cannot be paged and make Err return ErrPagingKeys.
+func (db *Database) IterAllDocs(ctx context.Context, params *QueryParameters, pageSize int) *AllDocsIterator {
+	return newAllDocsIterator(ctx, db, params, pageSize)
+}
+
+// docPath escapes a document ID for use in URLs.
+// The slash after the _design and _local prefixes must not be escaped.
+func docPath(id string) string {
+	for _, prefix := range []string{"_design/", "_local/"} {
+		if strings.HasPrefix(id, prefix) {
+			return prefix + url.PathEscape(strings.TrimPrefix(id, prefix))
+		}
+	}
+	return url.PathEscape(id)
+}
+
 // Head request.
 func (db *Database) Head(id string) (*http.Response, error) {
-	u := fmt.Sprintf("%s/%s", url.PathEscape(db.Name), url.PathEscape(id))
+	u := fmt.Sprintf("%s/%s", url.PathEscape(db.Name), docPath(id))
 	body, err := db.Client.Request(http.MethodHead, u, nil, "")
 	if err != nil {
 		return nil, err
@@ -94,7 +172,7 @@ func (db *Database) Head(id string) (*http.Response, error) {
 
 // Get document.
 func (db *Database) Get(doc CouchDoc, id string) error {
-	u := fmt.Sprintf("%s/%s", url.PathEscape(db.Name), url.PathEscape(id))
+	u := fmt.Sprintf("%s/%s", url.PathEscape(db.Name), docPath(id))
 	res, err := db.Client.Request(http.MethodGet, u, nil, "application/json")
 	if err != nil {
 		return err
@@ -105,7 +183,7 @@ func (db *Database) Get(doc CouchDoc, id string) error {
 
 // Put document.
 func (db *Database) Put(doc CouchDoc) (*DocumentResponse, error) {
-	u := fmt.Sprintf("%s/%s", url.PathEscape(db.Name), url.PathEscape(doc.GetID()))
+	u := fmt.Sprintf("%s/%s", url.PathEscape(db.Name), docPath(doc.GetID()))
 	var b bytes.Buffer
 	if err := json.NewEncoder(&b).Encode(doc); err != nil {
 		return nil, err
@@ -136,7 +214,7 @@ func (db *Database) Post(doc CouchDoc) (*DocumentResponse, error) {
 
 // Delete document.
 func (db *Database) Delete(doc CouchDoc) (*DocumentResponse, error) {
-	u := fmt.Sprintf("%s/%s?rev=%s", url.PathEscape(db.Name), url.PathEscape(doc.GetID()), doc.GetRev())
+	u := fmt.Sprintf("%s/%s?rev=%s", url.PathEscape(db.Name), docPath(doc.GetID()), doc.GetRev())
 	res, err := db.Client.Request(http.MethodDelete, u, nil, "application/json")
 	if err != nil {
 		return nil, err
@@ -150,7 +228,7 @@ func (db *Database) Delete(doc CouchDoc) (*DocumentResponse, error) {
 func (db *Database) PutAttachment(doc CouchDoc, path string) (*DocumentResponse, error) {
 
 	// target url
-	u := fmt.Sprintf("%s/%s", url.PathEscape(db.Name), url.PathEscape(doc.GetID()))
+	u := fmt.Sprintf("%s/%s", url.PathEscape(db.Name), docPath(doc.GetID()))
 
 	// get file from disk
 	file, err := os.Open(path)
@@ -228,6 +306,32 @@ func (db *Database) View(name string) ViewService {
 	}
 }
 
+// CompactView starts compacting the view indexes of the design document.
+// ddoc is the name of the design document without the "_design/" prefix.
+// Compaction runs in the background, use View.Info to check its progress.
+// http://docs.couchdb.org/en/latest/api/database/compact.html#db-compact-design-doc
+func (db *Database) CompactView(ddoc string) (*DatabaseResponse, error) {
+	u := fmt.Sprintf("%s/_compact/%s", url.PathEscape(db.Name), url.PathEscape(ddoc))
+	return db.maintenance(u)
+}
+
+// ViewCleanup removes view index files that are no longer used by any design document.
+// http://docs.couchdb.org/en/latest/api/database/compact.html#db-view-cleanup
+func (db *Database) ViewCleanup() (*DatabaseResponse, error) {
+	return db.maintenance(url.PathEscape(db.Name) + "/_view_cleanup")
+}
+
+// maintenance posts an empty JSON request to the maintenance URL u.
+func (db *Database) maintenance(u string) (*DatabaseResponse, error) {
+	res, err := db.Client.Request(http.MethodPost, u, nil, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	response := &DatabaseResponse{}
+	return response, json.NewDecoder(res.Body).Decode(response)
+}
+
 // PurgeResponse is response from POST request to the _purge URL.
 type PurgeResponse struct {
 	PurgeSeq float64 `json:"purge_seq"`
@@ -251,6 +355,90 @@ func (db *Database) Purge(req map[string][]string) (*PurgeResponse, error) {
 	return response, json.NewDecoder(res.Body).Decode(&response)
 }
 
+// revsBatchSize is the maximum number of document IDs
+// sent within a single _revs_diff or _missing_revs request.
+const revsBatchSize = 1000
+
+// RevsDiff is a single entry inside the response from POST request to the _revs_diff URL.
+type RevsDiff struct {
+	Missing           []string `json:"missing"`
+	PossibleAncestors []string `json:"possible_ancestors,omitempty"`
+}
+
+// RevsDiff returns the subset of the given revisions that do not exist in the database.
+// Large requests are split into multiple requests.
+//
+// http://docs.couchdb.org/en/latest/api/database/misc.html#db-revs-diff
+func (db *Database) RevsDiff(req map[string][]string) (map[string]RevsDiff, error) {
+	response := map[string]RevsDiff{}
+	for _, chunk := range chunkRevs(req) {
+		part := map[string]RevsDiff{}
+		if err := db.postRevs("_revs_diff", chunk, &part); err != nil {
+			return nil, err
+		}
+		for id, diff := range part {
+			response[id] = diff
+		}
+	}
+	return response, nil
+}
+
+// MissingRevsResponse is response from POST request to the _missing_revs URL.
+type MissingRevsResponse struct {
+	MissingRevs map[string][]string `json:"missing_revs"`
+}
+
+// MissingRevs returns the document revisions that do not exist in the database.
+// Large requests are split into multiple requests.
+//
+// http://docs.couchdb.org/en/latest/api/database/misc.html#db-missing-revs
+func (db *Database) MissingRevs(req map[string][]string) (*MissingRevsResponse, error) {
+	response := &MissingRevsResponse{
+		MissingRevs: map[string][]string{},
+	}
+	for _, chunk := range chunkRevs(req) {
+		var part MissingRevsResponse
+		if err := db.postRevs("_missing_revs", chunk, &part); err != nil {
+			return nil, err
+		}
+		for id, revs := range part.MissingRevs {
+			response.MissingRevs[id] = revs
+		}
+	}
+	return response, nil
+}
+
+func (db *Database) postRevs(endpoint string, req map[string][]string, v interface{}) error {
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(req); err != nil {
+		return err
+	}
+	u := fmt.Sprintf("%s/%s", url.PathEscape(db.Name), endpoint)
+	res, err := db.Client.Request(http.MethodPost, u, &b, "application/json")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// chunkRevs splits a document ID to revisions map into maps with at most revsBatchSize entries.
+func chunkRevs(req map[string][]string) []map[string][]string {
+	chunks := []map[string][]string{}
+	chunk := map[string][]string{}
+	for id, revs := range req {
+		if len(chunk) == revsBatchSize {
+			chunks = append(chunks, chunk)
+			chunk = map[string][]string{}
+		}
+		chunk[id] = revs
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
 // Element is single element inside Admins/Members in security document.
 type Element struct {
 	Names []string `json:"names"`
@@ -335,6 +523,15 @@ type difference struct {
 	deletions []DesignDocument
 }
 
+// functionsEqual compares the functions of a design document.
+// Missing and empty function maps are equal.
+func functionsEqual(a, b map[string]string) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
 func diff(cache, db []DesignDocument) difference {
 	di := difference{
 		additions: []DesignDocument{},
@@ -350,8 +547,13 @@ func diff(cache, db []DesignDocument) difference {
 			if d.ID == c.ID {
 				exists = true
 				// check for different map/reduce and language
+				// as well as different filter, show, list and update functions
 				// do not check for different revision
-				if !reflect.DeepEqual(c.Views, d.Views) {
+				if !reflect.DeepEqual(c.Views, d.Views) ||
+					!functionsEqual(c.Filters, d.Filters) ||
+					!functionsEqual(c.Shows, d.Shows) ||
+					!functionsEqual(c.Lists, d.Lists) ||
+					!functionsEqual(c.Updates, d.Updates) {
 					existsButDifferent = true
 				}
 			}
@@ -372,7 +574,8 @@ func diff(cache, db []DesignDocument) difference {
 			}
 		}
 		// do not delete internal design documents like _auth
-		if !exists && !strings.HasPrefix(d.Name(), "_") {
+		// and Mango indexes which are managed by SeedIndexes
+		if !exists && !strings.HasPrefix(d.Name(), "_") && d.Language != langQuery {
 			di.deletions = append(di.deletions, d)
 		}
 	}
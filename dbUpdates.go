@@ -0,0 +1,204 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+
+	"github.com/google/go-querystring/query"
+)
+
+// Values for DBUpdate.Type.
+const (
+	DBUpdateCreated = "created"
+	DBUpdateUpdated = "updated"
+	DBUpdateDeleted = "deleted"
+)
+
+// DBUpdatesParameters is struct to define url query parameters for the _db_updates URL.
+// http://docs.couchdb.org/en/latest/api/server/common.html#db-updates
+type DBUpdatesParameters struct {
+	Feed      string `url:"feed,omitempty"`
+	Since     Seq    `url:"since,omitempty"`
+	Timeout   *int   `url:"timeout,omitempty"`
+	Heartbeat *int   `url:"heartbeat,omitempty"`
+}
+
+// DBUpdate is a single event inside the _db_updates feed.
+// Seq is empty for CouchDB 1.x.
+type DBUpdate struct {
+	DBName string `json:"db_name"`
+	Type   string `json:"type"`
+	Seq    Seq    `json:"seq"`
+}
+
+// DBUpdatesResponse is response from GET request to the _db_updates URL.
+type DBUpdatesResponse struct {
+	Results []DBUpdate `json:"results"`
+	LastSeq Seq        `json:"last_seq"`
+}
+
+// DBUpdates returns database events like creations and deletions.
+// Only the normal and longpoll feeds are supported.
+// CouchDB 1.x answers a longpoll request with a single event
+// which is returned as the only result.
+//
+// http://docs.couchdb.org/en/latest/api/server/common.html#db-updates
+func (c *Client) DBUpdates(params *DBUpdatesParameters) (*DBUpdatesResponse, error) {
+	if params != nil && params.Feed != "" && params.Feed != FeedNormal && params.Feed != FeedLongpoll {
+		return nil, fmt.Errorf("couchdb: feed %q is not supported by DBUpdates", params.Feed)
+	}
+	res, err := c.dbUpdatesRequest(context.Background(), params)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var response struct {
+		DBUpdatesResponse
+		DBUpdate
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	if response.Results == nil && response.DBName != "" {
+		response.Results = []DBUpdate{response.DBUpdate}
+	}
+	return &response.DBUpdatesResponse, nil
+}
+
+func (c *Client) dbUpdatesRequest(ctx context.Context, params *DBUpdatesParameters) (*http.Response, error) {
+	q, err := query.Values(params)
+	if err != nil {
+		return nil, err
+	}
+	return c.RequestContext(ctx, http.MethodGet, "_db_updates?"+q.Encode(), nil, "")
+}
+
+// DBUpdatesStream consumes the continuous _db_updates feed.
+// It works like ChangesStream.
+type DBUpdatesStream struct {
+	updates chan DBUpdate
+	update  DBUpdate
+	cancel  context.CancelFunc
+	done    chan struct{}
+	once    sync.Once
+	err     error
+}
+
+// StreamDBUpdates starts consuming the continuous _db_updates feed.
+// The stream reconnects from the last seen sequence when the connection breaks or stalls.
+func (c *Client) StreamDBUpdates(ctx context.Context, params *DBUpdatesParameters, options StreamOptions) *DBUpdatesStream {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s := &DBUpdatesStream{
+		updates: make(chan DBUpdate),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	p := DBUpdatesParameters{}
+	if params != nil {
+		p = *params
+	}
+	options = options.withDefaults()
+	p.Feed = FeedContinuous
+	p.Heartbeat = options.heartbeat()
+	connect := func(ctx context.Context) (*http.Response, error) {
+		return c.dbUpdatesRequest(ctx, &p)
+	}
+	handle := func(data []byte, id string) error {
+		var row struct {
+			DBUpdate
+			LastSeq Seq `json:"last_seq"`
+		}
+		if err := json.Unmarshal(data, &row); err != nil {
+			return err
+		}
+		if !row.LastSeq.IsZero() {
+			p.Since = row.LastSeq
+			return nil
+		}
+		if !row.Seq.IsZero() {
+			p.Since = row.Seq
+		}
+		select {
+		case s.updates <- row.DBUpdate:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	go func() {
+		err := runFeed(ctx, options, framingLines, connect, handle)
+		if ctx.Err() != nil {
+			err = nil
+		}
+		s.err = err
+		close(s.updates)
+		close(s.done)
+	}()
+	return s
+}
+
+// Updates returns the channel events are delivered on.
+// It is closed when the stream ends.
+func (s *DBUpdatesStream) Updates() <-chan DBUpdate {
+	return s.updates
+}
+
+// Next blocks until the next event is available.
+// It returns false when the stream has ended.
+func (s *DBUpdatesStream) Next() bool {
+	update, ok := <-s.updates
+	if !ok {
+		return false
+	}
+	s.update = update
+	return true
+}
+
+// Update returns the current event.
+func (s *DBUpdatesStream) Update() DBUpdate {
+	return s.update
+}
+
+// Err returns the error that ended the stream.
+// It is nil while the stream is running or after it was closed.
+func (s *DBUpdatesStream) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Close stops the stream and waits until the connection is closed.
+// It is safe to call Close from another goroutine and more than once.
+func (s *DBUpdatesStream) Close() error {
+	s.once.Do(s.cancel)
+	<-s.done
+	return nil
+}
+
+// WatchDBUpdates calls handler for every event of a database whose name matches pattern.
+// A nil pattern matches all databases. It blocks until the context is canceled,
+// the handler returns an error or the stream fails.
+func (c *Client) WatchDBUpdates(ctx context.Context, pattern *regexp.Regexp, params *DBUpdatesParameters, options StreamOptions, handler func(DBUpdate) error) error {
+	stream := c.StreamDBUpdates(ctx, params, options)
+	defer stream.Close()
+	for stream.Next() {
+		update := stream.Update()
+		if pattern != nil && !pattern.MatchString(update.DBName) {
+			continue
+		}
+		if err := handler(update); err != nil {
+			return err
+		}
+	}
+	return stream.Err()
+}
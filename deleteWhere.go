@@ -0,0 +1,197 @@
+package couchdb
+
+import "context"
+
+// DeleteOptions configures the DeleteWhere helpers.
+type DeleteOptions struct {
+	// BatchSize is the number of documents deleted per _bulk_docs request.
+	// It defaults to DefaultBulkBatchSize.
+	BatchSize int
+	// DryRun only counts the matching documents without deleting them.
+	DryRun bool
+	// Progress is called after every batch with the current result.
+	Progress func(DeleteResult)
+}
+
+// DeleteResult summarizes a bulk deletion.
+type DeleteResult struct {
+	Matched int
+	Deleted int
+	// Failed contains the responses for documents CouchDB refused to delete, e.g. conflicts.
+	Failed []DocumentResponse
+}
+
+// tombstone is a deleted document inside a _bulk_docs request.
+type tombstone struct {
+	Document
+	Deleted bool `json:"_deleted"`
+}
+
+// deleter collects id/rev pairs and deletes them in batches.
+type deleter struct {
+	db      *Database
+	options DeleteOptions
+	result  DeleteResult
+	batch   []CouchDoc
+}
+
+func newDeleter(db *Database, options DeleteOptions) *deleter {
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultBulkBatchSize
+	}
+	return &deleter{
+		db:      db,
+		options: options,
+	}
+}
+
+func (d *deleter) add(id, rev string) error {
+	d.result.Matched++
+	d.batch = append(d.batch, &tombstone{
+		Document: Document{
+			ID:  id,
+			Rev: rev,
+		},
+		Deleted: true,
+	})
+	if len(d.batch) >= d.options.BatchSize {
+		return d.flush()
+	}
+	return nil
+}
+
+func (d *deleter) flush() error {
+	if len(d.batch) == 0 {
+		return nil
+	}
+	if !d.options.DryRun {
+		responses, err := d.db.Bulk(d.batch)
+		if err != nil {
+			return err
+		}
+		for _, res := range responses {
+			if res.Error != "" {
+				d.result.Failed = append(d.result.Failed, res)
+				continue
+			}
+			d.result.Deleted++
+		}
+	}
+	d.batch = nil
+	if d.options.Progress != nil {
+		d.options.Progress(d.result)
+	}
+	return nil
+}
+
+func (d *deleter) done() (*DeleteResult, error) {
+	if err := d.flush(); err != nil {
+		return &d.result, err
+	}
+	return &d.result, nil
+}
+
+// rowRev returns the current revision of a _all_docs row.
+// Missing and already deleted documents return false.
+func rowRev(row Row) (string, bool) {
+	if row.Error != "" {
+		return "", false
+	}
+	value, ok := row.Value.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	if deleted, _ := value["deleted"].(bool); deleted {
+		return "", false
+	}
+	rev, ok := value["rev"].(string)
+	return rev, ok
+}
+
+// DeleteWhereIDs deletes all documents with the given IDs.
+// IDs that do not exist or are already deleted are ignored.
+func (db *Database) DeleteWhereIDs(ids []string, options DeleteOptions) (*DeleteResult, error) {
+	d := newDeleter(db, options)
+	for start := 0; start < len(ids); start += d.options.BatchSize {
+		end := start + d.options.BatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		res, err := db.AllDocsKeys(ids[start:end], nil)
+		if err != nil {
+			return &d.result, err
+		}
+		for _, row := range res.Rows {
+			if rev, ok := rowRev(row); ok {
+				if err := d.add(row.ID, rev); err != nil {
+					return &d.result, err
+				}
+			}
+		}
+	}
+	return d.done()
+}
+
+// DeleteWhereRange deletes all documents whose IDs are between startKey and endKey, inclusive.
+// Design documents inside the range are deleted as well.
+func (db *Database) DeleteWhereRange(startKey, endKey string, options DeleteOptions) (*DeleteResult, error) {
+	d := newDeleter(db, options)
+	params := &QueryParameters{
+		StartKey: startKey,
+		EndKey:   endKey,
+	}
+	it := db.IterAllDocs(context.Background(), params, d.options.BatchSize)
+	for it.Next() {
+		row := it.Row()
+		if rev, ok := rowRev(row); ok {
+			if err := d.add(row.ID, rev); err != nil {
+				return &d.result, err
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return &d.result, err
+	}
+	return d.done()
+}
+
+// DeleteWhereSelector deletes all documents matching the given Mango selector.
+// All matches are collected before the first document is deleted.
+func (db *Database) DeleteWhereSelector(selector interface{}, options DeleteOptions) (*DeleteResult, error) {
+	d := newDeleter(db, options)
+	docs := []Document{}
+	bookmark := ""
+	for {
+		page, next, err := db.findRevs(selector, bookmark, d.options.BatchSize)
+		if err != nil {
+			return &d.result, err
+		}
+		docs = append(docs, page...)
+		if len(page) < d.options.BatchSize {
+			break
+		}
+		bookmark = next
+	}
+	for _, doc := range docs {
+		if err := d.add(doc.ID, doc.Rev); err != nil {
+			return &d.result, err
+		}
+	}
+	return d.done()
+}
+
+// findRevs returns one page of _id and _rev pairs matching selector.
+func (db *Database) findRevs(selector interface{}, bookmark string, limit int) ([]Document, string, error) {
+	query := FindQuery{
+		Selector: selector,
+		Fields:   []string{"_id", "_rev"},
+		Limit:    &limit,
+		Bookmark: bookmark,
+	}
+	docs := []Document{}
+	res, err := db.Find(query, &docs)
+	if err != nil {
+		return nil, "", err
+	}
+	return docs, res.Bookmark, nil
+}
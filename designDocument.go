@@ -4,6 +4,9 @@ import "strings"
 
 const langJavaScript = "javascript"
 
+// langQuery is the language of design documents holding Mango indexes.
+const langQuery = "query"
+
 // DesignDocument is a special type of CouchDB document that contains application code.
 // http://docs.couchdb.org/en/latest/json-structure.html#design-document
 type DesignDocument struct {
@@ -11,6 +14,9 @@ type DesignDocument struct {
 	Language string                        `json:"language,omitempty"`
 	Views    map[string]DesignDocumentView `json:"views,omitempty"`
 	Filters  map[string]string             `json:"filters,omitempty"`
+	Shows    map[string]string             `json:"shows,omitempty"`
+	Lists    map[string]string             `json:"lists,omitempty"`
+	Updates  map[string]string             `json:"updates,omitempty"`
 }
 
 // Name returns design document name without the "_design/" prefix
@@ -1,8 +1,11 @@
 package couchdb
 
 // DocumentResponse is response for multipart/related file upload.
+// Error and Reason are set for rejected documents in a _bulk_docs response.
 type DocumentResponse struct {
-	Ok  bool
-	ID  string
-	Rev string
+	Ok     bool
+	ID     string
+	Rev    string
+	Error  string
+	Reason string
 }
@@ -0,0 +1,197 @@
+package couchdb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ErrFeedStalled is returned when a streaming feed did not send any data,
+// including heartbeats, within StreamOptions.StallTimeout.
+var ErrFeedStalled = errors.New("couchdb: feed stalled")
+
+// errFeedDone is returned by a feed handler to end the feed without an error.
+var errFeedDone = errors.New("couchdb: feed done")
+
+// Default values for StreamOptions.
+const (
+	DefaultHeartbeat  = 10 * time.Second
+	DefaultMinBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff = 30 * time.Second
+)
+
+// StreamOptions configures streaming feeds like the continuous changes feed.
+// Zero values fall back to the defaults above.
+type StreamOptions struct {
+	// Heartbeat is the interval in which CouchDB sends empty lines to keep the connection alive.
+	Heartbeat time.Duration
+	// StallTimeout is the time without any data after which the connection
+	// is considered stalled and re-established. It defaults to three heartbeats.
+	StallTimeout time.Duration
+	// MinBackoff is the wait time before the first reconnect.
+	// It doubles with every consecutive failure up to MaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxRetries is the number of consecutive failed connections after which
+	// the feed gives up. Zero retries forever.
+	MaxRetries int
+}
+
+func (o StreamOptions) withDefaults() StreamOptions {
+	if o.Heartbeat <= 0 {
+		o.Heartbeat = DefaultHeartbeat
+	}
+	if o.StallTimeout <= 0 {
+		o.StallTimeout = 3 * o.Heartbeat
+	}
+	if o.MinBackoff <= 0 {
+		o.MinBackoff = DefaultMinBackoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = DefaultMaxBackoff
+	}
+	return o
+}
+
+// heartbeat returns the heartbeat in milliseconds for the heartbeat query parameter.
+func (o StreamOptions) heartbeat() *int {
+	ms := int(o.Heartbeat / time.Millisecond)
+	return &ms
+}
+
+// feedFraming describes how events are separated inside a streaming feed.
+type feedFraming int
+
+const (
+	// framingLines is used by continuous feeds with one JSON object per line.
+	framingLines feedFraming = iota
+	// framingEventSource is used by eventsource feeds.
+	// https://html.spec.whatwg.org/multipage/server-sent-events.html
+	framingEventSource
+)
+
+// feedHandler is called for every event inside a feed.
+// id is the last event id and only set for eventsource feeds.
+type feedHandler func(data []byte, id string) error
+
+// runFeed connects to a streaming feed and calls handle for every event.
+// Broken and stalled connections are re-established with exponential backoff.
+// connect is called for every connection and must resume the feed
+// where the last handled event left off.
+func runFeed(ctx context.Context, options StreamOptions, framing feedFraming, connect func(context.Context) (*http.Response, error), handle feedHandler) error {
+	failures := 0
+	for {
+		progress, err := readFeed(ctx, options, framing, connect, handle)
+		if err == errFeedDone {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if progress {
+			failures = 0
+		}
+		if err != nil {
+			// client errors like a missing database do not go away by retrying
+			if cerr, ok := err.(*Error); ok && cerr.StatusCode < http.StatusInternalServerError {
+				return err
+			}
+			failures++
+			if options.MaxRetries > 0 && failures > options.MaxRetries {
+				return err
+			}
+		}
+		wait := backoff(options, failures)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// readFeed reads a single connection until it ends.
+// progress reports whether at least one event has been handled.
+func readFeed(ctx context.Context, options StreamOptions, framing feedFraming, connect func(context.Context) (*http.Response, error), handle feedHandler) (progress bool, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var stalled int32
+	timer := time.AfterFunc(options.StallTimeout, func() {
+		atomic.StoreInt32(&stalled, 1)
+		cancel()
+	})
+	defer timer.Stop()
+	res, err := connect(ctx)
+	if err != nil {
+		if atomic.LoadInt32(&stalled) == 1 {
+			return false, ErrFeedStalled
+		}
+		return false, err
+	}
+	defer res.Body.Close()
+	r := bufio.NewReader(res.Body)
+	var (
+		data []byte
+		id   string
+	)
+	for {
+		line, err := r.ReadBytes('\n')
+		// do not count time spent in the handler as stalled
+		timer.Stop()
+		line = bytes.TrimRight(line, "\r\n")
+		var event []byte
+		switch framing {
+		case framingLines:
+			event = bytes.TrimSpace(line)
+		case framingEventSource:
+			// a blank line dispatches the event, lines starting with a colon are comments
+			if len(line) == 0 && err == nil {
+				event, data = bytes.TrimSuffix(data, []byte("\n")), nil
+				break
+			}
+			field, value := line, []byte{}
+			if i := bytes.IndexByte(line, ':'); i >= 0 {
+				field, value = line[:i], bytes.TrimPrefix(line[i+1:], []byte(" "))
+			}
+			switch string(field) {
+			case "data":
+				data = append(append(data, value...), '\n')
+			case "id":
+				id = string(value)
+			}
+		}
+		if len(event) > 0 {
+			if err := handle(event, id); err != nil {
+				return progress, err
+			}
+			progress = true
+		}
+		if err != nil {
+			if atomic.LoadInt32(&stalled) == 1 {
+				return progress, ErrFeedStalled
+			}
+			if err == io.EOF {
+				return progress, nil
+			}
+			return progress, err
+		}
+		timer.Reset(options.StallTimeout)
+	}
+}
+
+// backoff returns the wait time before the next connection.
+func backoff(options StreamOptions, failures int) time.Duration {
+	wait := options.MinBackoff
+	for i := 1; i < failures && wait < options.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > options.MaxBackoff {
+		wait = options.MaxBackoff
+	}
+	return wait
+}
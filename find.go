@@ -0,0 +1,168 @@
+package couchdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// FindQuery is the body of POST request to the _find URL.
+// Selector is usually built with the Selector functions but
+// any value that marshals to a valid selector is accepted.
+// UseIndex is either a design document name or a slice with
+// design document name and index name.
+// http://docs.couchdb.org/en/latest/api/database/find.html#db-find
+type FindQuery struct {
+	Selector       interface{}         `json:"selector"`
+	Fields         []string            `json:"fields,omitempty"`
+	Sort           []map[string]string `json:"sort,omitempty"`
+	Limit          *int                `json:"limit,omitempty"`
+	Skip           *int                `json:"skip,omitempty"`
+	UseIndex       interface{}         `json:"use_index,omitempty"`
+	R              *int                `json:"r,omitempty"`
+	Bookmark       string              `json:"bookmark,omitempty"`
+	Update         *bool               `json:"update,omitempty"`
+	Stable         *bool               `json:"stable,omitempty"`
+	ExecutionStats bool                `json:"execution_stats,omitempty"`
+}
+
+// Asc returns a sort field for FindQuery.Sort in ascending order.
+func Asc(name string) map[string]string {
+	return map[string]string{name: "asc"}
+}
+
+// Desc returns a sort field for FindQuery.Sort in descending order.
+func Desc(name string) map[string]string {
+	return map[string]string{name: "desc"}
+}
+
+// FindResponse is response from POST request to the _find URL.
+// Docs holds the raw documents which Find decodes into the caller's type.
+type FindResponse struct {
+	Docs           json.RawMessage `json:"docs"`
+	Warning        string          `json:"warning,omitempty"`
+	ExecutionStats *ExecutionStats `json:"execution_stats,omitempty"`
+	Bookmark       string          `json:"bookmark,omitempty"`
+}
+
+// ExecutionStats describes the work done by a _find query.
+// http://docs.couchdb.org/en/latest/api/database/find.html#execution-statistics
+type ExecutionStats struct {
+	TotalKeysExamined       int     `json:"total_keys_examined"`
+	TotalDocsExamined       int     `json:"total_docs_examined"`
+	TotalQuorumDocsExamined int     `json:"total_quorum_docs_examined"`
+	ResultsReturned         int     `json:"results_returned"`
+	ExecutionTimeMs         float64 `json:"execution_time_ms"`
+}
+
+// Find returns documents matching the query.
+// The documents are decoded into docs which must be a pointer to a slice.
+// docs may be nil to only use the raw documents inside the response.
+//
+// http://docs.couchdb.org/en/latest/api/database/find.html#db-find
+func (db *Database) Find(query FindQuery, docs interface{}) (*FindResponse, error) {
+	response, err := db.find(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	if docs != nil {
+		return response, json.Unmarshal(response.Docs, docs)
+	}
+	return response, nil
+}
+
+func (db *Database) find(ctx context.Context, query FindQuery) (*FindResponse, error) {
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(query); err != nil {
+		return nil, err
+	}
+	u := fmt.Sprintf("%s/_find", url.PathEscape(db.Name))
+	res, err := db.Client.RequestContext(ctx, http.MethodPost, u, &b, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	response := &FindResponse{}
+	return response, json.NewDecoder(res.Body).Decode(response)
+}
+
+// IterFind returns an iterator over all documents matching the query.
+// Documents are fetched lazily in pages of pageSize documents.
+func (db *Database) IterFind(ctx context.Context, query FindQuery, pageSize int) *FindIterator {
+	return newFindIterator(ctx, db, query, pageSize)
+}
+
+// ExplainResponse is response from POST request to the _explain URL.
+// Index is the index CouchDB picked for the query.
+// http://docs.couchdb.org/en/latest/api/database/find.html#db-explain
+type ExplainResponse struct {
+	DBName      string                 `json:"dbname"`
+	Index       IndexInfo              `json:"index"`
+	Partitioned bool                   `json:"partitioned"`
+	Selector    map[string]interface{} `json:"selector"`
+	Options     map[string]interface{} `json:"opts"`
+	Limit       int                    `json:"limit"`
+	Skip        int                    `json:"skip"`
+	// Fields is either the string "all_fields" or a slice of field names.
+	Fields interface{}  `json:"fields"`
+	Range  ExplainRange `json:"mrargs"`
+	// Covering and IndexCandidates are only returned by CouchDB 3.3 and later.
+	Covering        *bool            `json:"covering,omitempty"`
+	IndexCandidates []IndexCandidate `json:"index_candidates,omitempty"`
+}
+
+// ExplainRange is the range of the chosen index scanned by the query.
+type ExplainRange struct {
+	StartKey    interface{} `json:"start_key"`
+	EndKey      interface{} `json:"end_key"`
+	Direction   string      `json:"direction"`
+	IncludeDocs bool        `json:"include_docs"`
+	Reduce      bool        `json:"reduce"`
+	ViewType    string      `json:"view_type"`
+}
+
+// IndexCandidate is an index CouchDB considered but did not pick.
+type IndexCandidate struct {
+	Index    IndexInfo     `json:"index"`
+	Analysis IndexAnalysis `json:"analysis"`
+}
+
+// IndexAnalysis explains why an index candidate was rejected.
+type IndexAnalysis struct {
+	Usable   bool          `json:"usable"`
+	Reasons  []IndexReason `json:"reasons"`
+	Ranking  int           `json:"ranking"`
+	Covering *bool         `json:"covering"`
+}
+
+// IndexReason is a single reason for rejecting an index candidate, e.g. "field_mismatch".
+type IndexReason struct {
+	Name string `json:"name"`
+}
+
+// UsesAllDocs reports whether the query falls back to the _all_docs index,
+// i.e. every document in the database is scanned.
+func (e *ExplainResponse) UsesAllDocs() bool {
+	return e.Index.Type == IndexTypeSpecial && e.Index.Name == "_all_docs"
+}
+
+// Explain returns the query plan CouchDB uses for the query.
+//
+// http://docs.couchdb.org/en/latest/api/database/find.html#db-explain
+func (db *Database) Explain(query FindQuery) (*ExplainResponse, error) {
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(query); err != nil {
+		return nil, err
+	}
+	u := fmt.Sprintf("%s/_explain", url.PathEscape(db.Name))
+	res, err := db.Client.Request(http.MethodPost, u, &b, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	response := &ExplainResponse{}
+	return response, json.NewDecoder(res.Body).Decode(response)
+}
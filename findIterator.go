@@ -0,0 +1,129 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// FindIterator iterates over the documents matching a Mango query.
+// Pages are requested with the bookmark returned by the previous page
+// until a page contains fewer documents than requested.
+//
+//	it := db.IterFind(ctx, FindQuery{Selector: Eq("type", "user")}, 100)
+//	for it.Next() {
+//	  var user User
+//	  if err := it.Decode(&user); err != nil {
+//	    // handle error
+//	  }
+//	}
+//	if err := it.Err(); err != nil {
+//	  // handle error
+//	}
+type FindIterator struct {
+	ctx      context.Context
+	db       *Database
+	query    FindQuery
+	pageSize int
+	// remaining is the number of documents left if query.Limit was set, -1 otherwise
+	remaining int
+	docs      []json.RawMessage
+	doc       json.RawMessage
+	bookmark  string
+	last      bool
+	err       error
+}
+
+func newFindIterator(ctx context.Context, db *Database, query FindQuery, pageSize int) *FindIterator {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	it := &FindIterator{
+		ctx:       ctx,
+		db:        db,
+		query:     query,
+		pageSize:  pageSize,
+		remaining: -1,
+		bookmark:  query.Bookmark,
+	}
+	if query.Limit != nil {
+		it.remaining = *query.Limit
+	}
+	return it
+}
+
+// Next advances the iterator to the next document. It returns false when there are
+// no more documents, an error occurred or the context was canceled.
+func (it *FindIterator) Next() bool {
+	if it.err != nil || it.remaining == 0 {
+		return false
+	}
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+	if len(it.docs) == 0 {
+		if it.last {
+			return false
+		}
+		if err := it.fetch(); err != nil {
+			it.err = err
+			return false
+		}
+		if len(it.docs) == 0 {
+			return false
+		}
+	}
+	it.doc, it.docs = it.docs[0], it.docs[1:]
+	if it.remaining > 0 {
+		it.remaining--
+	}
+	return true
+}
+
+// Decode decodes the current document into v.
+func (it *FindIterator) Decode(v interface{}) error {
+	if it.doc == nil {
+		return errors.New("couchdb: Decode called without a current document")
+	}
+	return json.Unmarshal(it.doc, v)
+}
+
+// Bookmark returns the bookmark of the last fetched page.
+// A new query with this bookmark continues after the current page.
+// Resuming is exact once all documents of the current page were read.
+func (it *FindIterator) Bookmark() string {
+	return it.bookmark
+}
+
+// Err returns the first error that occurred during iteration.
+func (it *FindIterator) Err() error {
+	return it.err
+}
+
+// fetch requests the next page starting at the current bookmark.
+func (it *FindIterator) fetch() error {
+	limit := it.pageSize
+	if it.remaining > 0 && it.remaining < limit {
+		limit = it.remaining
+	}
+	it.query.Limit = &limit
+	it.query.Bookmark = it.bookmark
+	res, err := it.db.find(it.ctx, it.query)
+	if err != nil {
+		return err
+	}
+	docs := []json.RawMessage{}
+	if err := json.Unmarshal(res.Docs, &docs); err != nil {
+		return err
+	}
+	// skip only applies to the first page
+	it.query.Skip = nil
+	it.docs = docs
+	it.bookmark = res.Bookmark
+	it.last = len(docs) < limit
+	return nil
+}
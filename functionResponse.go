@@ -0,0 +1,34 @@
+package couchdb
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// FunctionResponse is the raw response of a show or list function.
+// The caller must close Body, either directly or through Close or Decode.
+type FunctionResponse struct {
+	Body        io.ReadCloser
+	ContentType string
+	Header      http.Header
+}
+
+func newFunctionResponse(res *http.Response) *FunctionResponse {
+	return &FunctionResponse{
+		Body:        res.Body,
+		ContentType: res.Header.Get("Content-Type"),
+		Header:      res.Header,
+	}
+}
+
+// Decode decodes a JSON body into v and closes the body.
+func (r *FunctionResponse) Decode(v interface{}) error {
+	defer r.Body.Close()
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
+// Close closes the body.
+func (r *FunctionResponse) Close() error {
+	return r.Body.Close()
+}
@@ -0,0 +1,271 @@
+package couchdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// Values for IndexDefinition.Type and IndexInfo.Type.
+// The special type is only used by the built-in _all_docs index.
+const (
+	IndexTypeJSON    = "json"
+	IndexTypeText    = "text"
+	IndexTypeSpecial = "special"
+)
+
+// Index describes the fields of a Mango index.
+// Fields of json indexes map the field name to the sort order, e.g. Asc("name").
+// Fields of text indexes map the field name to its type, e.g. {"name": "string"}.
+// DefaultField and Selector are only used by text indexes.
+type Index struct {
+	Fields                []map[string]string `json:"fields"`
+	PartialFilterSelector interface{}         `json:"partial_filter_selector,omitempty"`
+	DefaultField          interface{}         `json:"default_field,omitempty"`
+	Selector              interface{}         `json:"selector,omitempty"`
+}
+
+// IndexDefinition is the body of POST request to the _index URL.
+// http://docs.couchdb.org/en/latest/api/database/find.html#db-index
+type IndexDefinition struct {
+	Index       Index  `json:"index"`
+	DDoc        string `json:"ddoc,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Partitioned *bool  `json:"partitioned,omitempty"`
+}
+
+// CreateIndexResponse is response from POST request to the _index URL.
+// Result is either "created" or "exists".
+type CreateIndexResponse struct {
+	Result string `json:"result"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+}
+
+// IndexInfo is a single index inside the response from GET request to the _index URL.
+// DDoc is empty for the _all_docs index.
+type IndexInfo struct {
+	DDoc        string `json:"ddoc"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Partitioned bool   `json:"partitioned"`
+	Def         Index  `json:"def"`
+}
+
+// IndexList is response from GET request to the _index URL.
+type IndexList struct {
+	TotalRows int         `json:"total_rows"`
+	Indexes   []IndexInfo `json:"indexes"`
+}
+
+// CreateIndex creates a new Mango index.
+// Creating an index that already exists is not an error.
+//
+// http://docs.couchdb.org/en/latest/api/database/find.html#post--db-_index
+func (db *Database) CreateIndex(index IndexDefinition) (*CreateIndexResponse, error) {
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(index); err != nil {
+		return nil, err
+	}
+	u := fmt.Sprintf("%s/_index", url.PathEscape(db.Name))
+	res, err := db.Client.Request(http.MethodPost, u, &b, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	response := &CreateIndexResponse{}
+	return response, json.NewDecoder(res.Body).Decode(response)
+}
+
+// ListIndexes returns all Mango indexes including the special _all_docs index.
+//
+// http://docs.couchdb.org/en/latest/api/database/find.html#get--db-_index
+func (db *Database) ListIndexes() (*IndexList, error) {
+	u := fmt.Sprintf("%s/_index", url.PathEscape(db.Name))
+	res, err := db.Client.Request(http.MethodGet, u, nil, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	response := &IndexList{}
+	return response, json.NewDecoder(res.Body).Decode(response)
+}
+
+// DeleteIndex removes the index with the given type and name from the design document,
+// e.g. DeleteIndex("people", IndexTypeText, "by-name").
+// ddoc may be given with or without the "_design/" prefix.
+// The type of an existing index is returned by ListIndexes.
+//
+// http://docs.couchdb.org/en/latest/api/database/find.html#delete--db-_index-designdoc-json-name
+func (db *Database) DeleteIndex(ddoc, typ, name string) (*DatabaseResponse, error) {
+	u := fmt.Sprintf("%s/_index/%s/%s/%s",
+		url.PathEscape(db.Name),
+		url.PathEscape(strings.TrimPrefix(ddoc, "_design/")),
+		typ,
+		url.PathEscape(name),
+	)
+	res, err := db.Client.Request(http.MethodDelete, u, nil, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	response := &DatabaseResponse{}
+	return response, json.NewDecoder(res.Body).Decode(response)
+}
+
+// SeedIndexesOptions configures SeedIndexes.
+type SeedIndexesOptions struct {
+	// DryRun only computes the plan without changing any index.
+	DryRun bool
+	// Prune deletes existing indexes that are not part of the desired indexes.
+	Prune bool
+}
+
+// IndexPlan lists the changes SeedIndexes makes to the Mango indexes.
+// Replaced indexes are deleted and created again.
+type IndexPlan struct {
+	Create  []IndexDefinition
+	Replace []IndexDefinition
+	Delete  []IndexInfo
+}
+
+// SeedIndexes makes sure all your Mango indexes are up to date.
+// Indexes are identified by design document and name, so both must be set.
+// An index is replaced if its type, fields or partial filter selector differ.
+// Partial filter selectors are compared as CouchDB stores them, i.e. with explicit
+// operators like the ones built by Eq and And.
+func (db *Database) SeedIndexes(indexes []IndexDefinition, options SeedIndexesOptions) (*IndexPlan, error) {
+	for _, index := range indexes {
+		if index.DDoc == "" || index.Name == "" {
+			return nil, fmt.Errorf("couchdb: index %v needs both ddoc and name", index.Index.Fields)
+		}
+	}
+	list, err := db.ListIndexes()
+	if err != nil {
+		return nil, err
+	}
+	plan, err := diffIndexes(indexes, list.Indexes, options.Prune)
+	if err != nil {
+		return nil, err
+	}
+	if options.DryRun {
+		return &plan, nil
+	}
+	for _, info := range plan.Delete {
+		if _, err := db.DeleteIndex(info.DDoc, info.Type, info.Name); err != nil {
+			return &plan, err
+		}
+	}
+	for _, index := range plan.Replace {
+		for _, info := range list.Indexes {
+			if indexKey(info.DDoc, info.Name) == indexKey(index.DDoc, index.Name) {
+				if _, err := db.DeleteIndex(info.DDoc, info.Type, info.Name); err != nil {
+					return &plan, err
+				}
+			}
+		}
+		if _, err := db.CreateIndex(index); err != nil {
+			return &plan, err
+		}
+	}
+	for _, index := range plan.Create {
+		if _, err := db.CreateIndex(index); err != nil {
+			return &plan, err
+		}
+	}
+	return &plan, nil
+}
+
+// indexKey identifies an index by design document and name.
+func indexKey(ddoc, name string) string {
+	return strings.TrimPrefix(ddoc, "_design/") + "/" + name
+}
+
+func diffIndexes(desired []IndexDefinition, existing []IndexInfo, prune bool) (IndexPlan, error) {
+	plan := IndexPlan{
+		Create:  []IndexDefinition{},
+		Replace: []IndexDefinition{},
+		Delete:  []IndexInfo{},
+	}
+	current := map[string]IndexInfo{}
+	for _, info := range existing {
+		if info.Type == IndexTypeSpecial {
+			continue
+		}
+		current[indexKey(info.DDoc, info.Name)] = info
+	}
+	managed := map[string]bool{}
+	for _, index := range desired {
+		key := indexKey(index.DDoc, index.Name)
+		managed[key] = true
+		info, ok := current[key]
+		if !ok {
+			plan.Create = append(plan.Create, index)
+			continue
+		}
+		equal, err := indexEqual(index, info)
+		if err != nil {
+			return plan, err
+		}
+		if !equal {
+			plan.Replace = append(plan.Replace, index)
+		}
+	}
+	if prune {
+		for _, info := range existing {
+			if info.Type != IndexTypeSpecial && !managed[indexKey(info.DDoc, info.Name)] {
+				plan.Delete = append(plan.Delete, info)
+			}
+		}
+	}
+	return plan, nil
+}
+
+// indexEqual compares the type, fields and partial filter selector of
+// the desired index with an existing one.
+func indexEqual(index IndexDefinition, info IndexInfo) (bool, error) {
+	typ := index.Type
+	if typ == "" {
+		typ = IndexTypeJSON
+	}
+	if typ != info.Type {
+		return false, nil
+	}
+	if !reflect.DeepEqual(index.Index.Fields, info.Def.Fields) {
+		return false, nil
+	}
+	desired, err := normalizeSelector(index.Index.PartialFilterSelector)
+	if err != nil {
+		return false, err
+	}
+	current, err := normalizeSelector(info.Def.PartialFilterSelector)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(desired, current), nil
+}
+
+// normalizeSelector round trips selector through JSON so that selectors built
+// with Go types compare equal to decoded ones. Empty selectors become nil.
+func normalizeSelector(selector interface{}) (interface{}, error) {
+	if selector == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(selector)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	if m, ok := v.(map[string]interface{}); ok && len(m) == 0 {
+		return nil, nil
+	}
+	return v, nil
+}
@@ -1,24 +1,200 @@
 package couchdb
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/google/go-querystring/query"
+)
+
+// Values for QueryParameters.Update.
+const (
+	ViewUpdateTrue  = "true"
+	ViewUpdateFalse = "false"
+	ViewUpdateLazy  = "lazy"
+)
+
 // QueryParameters is struct to define url query parameters for design documents.
+// Key, Keys, StartKey and EndKey take any value and are JSON encoded,
+// e.g. StartKey: []interface{}{"user", 42} becomes startkey=["user",42].
+// The JSON form is used for the bodies of multi query requests.
+// StartKeyAlias, EndKeyAlias, StartKeyDocIDAlias and EndKeyDocIDAlias are sent as
+// start_key, end_key, start_key_doc_id and end_key_doc_id which CouchDB treats
+// exactly like StartKey, EndKey, StartKeyDocID and EndKeyDocID.
+// Only one spelling of each parameter may be set.
+// Stable, Update and Sorted require CouchDB 2.0 or later
+// and replace the deprecated Stale.
 // http://docs.couchdb.org/en/latest/api/ddoc/views.html#db-design-design-doc-view-view-name
 type QueryParameters struct {
-	Conflicts       *bool   `url:"conflicts,omitempty"`
-	Descending      *bool   `url:"descending,omitempty"`
-	Group           *bool   `url:"group,omitempty"`
-	IncludeDocs     *bool   `url:"include_docs,omitempty"`
-	Attachments     *bool   `url:"attachments,omitempty"`
-	AttEncodingInfo *bool   `url:"att_encoding_info,omitempty"`
-	InclusiveEnd    *bool   `url:"inclusive_end,omitempty"`
-	Reduce          *bool   `url:"reduce,omitempty"`
-	UpdateSeq       *bool   `url:"update_seq,omitempty"`
-	GroupLevel      *int    `url:"group_level,omitempty"`
-	Limit           *int    `url:"limit,omitempty"`
-	Skip            *int    `url:"skip,omitempty"`
-	Key             *string `url:"key,omitempty"`
-	EndKey          *string `url:"endkey,comma,omitempty"`
-	EndKeyDocID     *string `url:"end_key_doc_id,omitempty"`
-	Stale           *string `url:"stale,omitempty"`
-	StartKey        *string `url:"startkey,comma,omitempty"`
-	StartKeyDocID   *string `url:"startkey_docid,omitempty"`
+	Conflicts       *bool         `url:"conflicts,omitempty" json:"conflicts,omitempty"`
+	Descending      *bool         `url:"descending,omitempty" json:"descending,omitempty"`
+	Group           *bool         `url:"group,omitempty" json:"group,omitempty"`
+	IncludeDocs     *bool         `url:"include_docs,omitempty" json:"include_docs,omitempty"`
+	Attachments     *bool         `url:"attachments,omitempty" json:"attachments,omitempty"`
+	AttEncodingInfo *bool         `url:"att_encoding_info,omitempty" json:"att_encoding_info,omitempty"`
+	InclusiveEnd    *bool         `url:"inclusive_end,omitempty" json:"inclusive_end,omitempty"`
+	Reduce          *bool         `url:"reduce,omitempty" json:"reduce,omitempty"`
+	Sorted          *bool         `url:"sorted,omitempty" json:"sorted,omitempty"`
+	Stable          *bool         `url:"stable,omitempty" json:"stable,omitempty"`
+	UpdateSeq       *bool         `url:"update_seq,omitempty" json:"update_seq,omitempty"`
+	GroupLevel      *int          `url:"group_level,omitempty" json:"group_level,omitempty"`
+	Limit           *int          `url:"limit,omitempty" json:"limit,omitempty"`
+	Skip            *int          `url:"skip,omitempty" json:"skip,omitempty"`
+	Key             interface{}   `url:"-" json:"key,omitempty"`
+	Keys            []interface{} `url:"-" json:"keys,omitempty"`
+	EndKey          interface{}   `url:"-" json:"endkey,omitempty"`
+	EndKeyDocID     *string       `url:"endkey_docid,omitempty" json:"endkey_docid,omitempty"`
+	Stale           *string       `url:"stale,omitempty" json:"stale,omitempty"`
+	StartKey        interface{}   `url:"-" json:"startkey,omitempty"`
+	StartKeyDocID   *string       `url:"startkey_docid,omitempty" json:"startkey_docid,omitempty"`
+	Update          *string       `url:"update,omitempty" json:"update,omitempty"`
+
+	StartKeyAlias      interface{} `url:"-" json:"start_key,omitempty"`
+	EndKeyAlias        interface{} `url:"-" json:"end_key,omitempty"`
+	StartKeyDocIDAlias *string     `url:"start_key_doc_id,omitempty" json:"start_key_doc_id,omitempty"`
+	EndKeyDocIDAlias   *string     `url:"end_key_doc_id,omitempty" json:"end_key_doc_id,omitempty"`
+}
+
+// Validate checks for parameter combinations CouchDB rejects or ignores.
+// It is called before every request that uses the parameters.
+func (params *QueryParameters) Validate() error {
+	if params == nil {
+		return nil
+	}
+	reduce := params.Reduce == nil || *params.Reduce
+	group := (params.Group != nil && *params.Group) || params.GroupLevel != nil
+	startKey := params.StartKey != nil || params.StartKeyAlias != nil
+	endKey := params.EndKey != nil || params.EndKeyAlias != nil
+	switch {
+	case params.StartKey != nil && params.StartKeyAlias != nil:
+		return errors.New("couchdb: startkey cannot be combined with its alias start_key")
+	case params.EndKey != nil && params.EndKeyAlias != nil:
+		return errors.New("couchdb: endkey cannot be combined with its alias end_key")
+	case params.StartKeyDocID != nil && params.StartKeyDocIDAlias != nil:
+		return errors.New("couchdb: startkey_docid cannot be combined with its alias start_key_doc_id")
+	case params.EndKeyDocID != nil && params.EndKeyDocIDAlias != nil:
+		return errors.New("couchdb: endkey_docid cannot be combined with its alias end_key_doc_id")
+	case group && !reduce:
+		return errors.New("couchdb: group and group_level require reduce")
+	case params.Reduce != nil && *params.Reduce && params.IncludeDocs != nil && *params.IncludeDocs:
+		return errors.New("couchdb: include_docs is invalid for reduce")
+	case params.Keys != nil && (params.Key != nil || startKey || endKey):
+		return errors.New("couchdb: keys cannot be combined with key, startkey or endkey")
+	case params.Key != nil && (startKey || endKey):
+		return errors.New("couchdb: key cannot be combined with startkey or endkey")
+	case params.Stale != nil && (params.Stable != nil || params.Update != nil):
+		return errors.New("couchdb: stale cannot be combined with stable or update")
+	case params.Stale != nil && *params.Stale != "ok" && *params.Stale != "update_after":
+		return fmt.Errorf("couchdb: stale must be ok or update_after but is %q", *params.Stale)
+	case params.Update != nil && *params.Update != ViewUpdateTrue && *params.Update != ViewUpdateFalse && *params.Update != ViewUpdateLazy:
+		return fmt.Errorf("couchdb: update must be true, false or lazy but is %q", *params.Update)
+	case params.Limit != nil && *params.Limit < 0:
+		return fmt.Errorf("couchdb: limit must not be negative but is %d", *params.Limit)
+	case params.Skip != nil && *params.Skip < 0:
+		return fmt.Errorf("couchdb: skip must not be negative but is %d", *params.Skip)
+	case params.GroupLevel != nil && *params.GroupLevel < 0:
+		return fmt.Errorf("couchdb: group_level must not be negative but is %d", *params.GroupLevel)
+	}
+	return nil
+}
+
+// ErrPagingKeys is returned by paginators for parameters with Keys.
+// Pages are continued with startkey which CouchDB rejects together with keys.
+var ErrPagingKeys = errors.New("couchdb: keys cannot be paged, split them into several requests instead")
+
+// pagingParams prepares params for paginators that move StartKey forward.
+// The aliases are folded into StartKey, EndKey, StartKeyDocID and EndKeyDocID.
+// Key becomes an equal StartKey and EndKey so that every page stays
+// within the rows of Key.
+func pagingParams(params QueryParameters) (QueryParameters, error) {
+	if err := params.Validate(); err != nil {
+		return params, err
+	}
+	if params.Keys != nil {
+		return params, ErrPagingKeys
+	}
+	if params.StartKeyAlias != nil {
+		params.StartKey = params.StartKeyAlias
+		params.StartKeyAlias = nil
+	}
+	if params.EndKeyAlias != nil {
+		params.EndKey = params.EndKeyAlias
+		params.EndKeyAlias = nil
+	}
+	if params.StartKeyDocIDAlias != nil {
+		params.StartKeyDocID = params.StartKeyDocIDAlias
+		params.StartKeyDocIDAlias = nil
+	}
+	if params.EndKeyDocIDAlias != nil {
+		params.EndKeyDocID = params.EndKeyDocIDAlias
+		params.EndKeyDocIDAlias = nil
+	}
+	if params.Key != nil {
+		params.StartKey = params.Key
+		params.EndKey = params.Key
+		params.Key = nil
+	}
+	return params, nil
+}
+
+// values returns the url query parameters with all keys JSON encoded.
+func (params *QueryParameters) values() (url.Values, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	q, err := query.Values(params)
+	if err != nil {
+		return nil, err
+	}
+	if params == nil {
+		return q, nil
+	}
+	keys := map[string]interface{}{
+		"key":       params.Key,
+		"startkey":  params.StartKey,
+		"endkey":    params.EndKey,
+		"start_key": params.StartKeyAlias,
+		"end_key":   params.EndKeyAlias,
+	}
+	if params.Keys != nil {
+		keys["keys"] = params.Keys
+	}
+	for name, value := range keys {
+		if value == nil {
+			continue
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		q.Set(name, string(b))
+	}
+	return q, nil
+}
+
+// HighKey returns the empty object {} which sorts after all other keys
+// in CouchDB's view collation. Use it as the last element of an EndKey array
+// to match all keys starting with the other elements,
+// e.g. []interface{}{"user", HighKey()}.
+// Every call returns a new object so changes to it cannot leak into other queries.
+func HighKey() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+// PrefixRange returns the start and end key of all string keys starting with prefix.
+//
+//	start, end := PrefixRange("abc")
+//	params := QueryParameters{StartKey: start, EndKey: end}
+func PrefixRange(prefix string) (startKey, endKey string) {
+	return prefix, prefix + "\ufff0"
+}
+
+// ArrayPrefixRange returns the start and end key of all array keys
+// whose first elements are equal to prefix.
+func ArrayPrefixRange(prefix ...interface{}) (startKey, endKey []interface{}) {
+	startKey = append([]interface{}{}, prefix...)
+	endKey = append(append([]interface{}{}, prefix...), HighKey())
+	return startKey, endKey
 }
@@ -0,0 +1,138 @@
+package couchdb
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Group levels for View.Reduce and View.ReduceRows.
+// Positive values group by that many elements of array keys.
+const (
+	// GroupNone reduces all rows into a single row with a null key.
+	GroupNone = 0
+	// GroupExact groups rows with exactly the same key.
+	GroupExact = -1
+)
+
+// Stats is the value of the built-in _stats reducer.
+type Stats struct {
+	Sum    float64 `json:"sum"`
+	Count  int     `json:"count"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Sumsqr float64 `json:"sumsqr"`
+}
+
+// ReduceRow is a single row of a reduced view.
+// The value is decoded with the method matching the reduce function.
+type ReduceRow struct {
+	Key   json.RawMessage `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// DecodeKey decodes the key into v.
+func (r ReduceRow) DecodeKey(v interface{}) error {
+	return json.Unmarshal(r.Key, v)
+}
+
+// Decode decodes the value of a custom reduce function into v.
+func (r ReduceRow) Decode(v interface{}) error {
+	return json.Unmarshal(r.Value, v)
+}
+
+// Count returns the value of the built-in _count reducer.
+func (r ReduceRow) Count() (int, error) {
+	return r.decodeInt()
+}
+
+// Sum returns the value of the built-in _sum reducer for numbers.
+func (r ReduceRow) Sum() (float64, error) {
+	var sum float64
+	if err := json.Unmarshal(r.Value, &sum); err != nil {
+		return 0, err
+	}
+	return sum, nil
+}
+
+// Sums returns the value of the built-in _sum reducer for arrays of numbers.
+// Element i is the sum of element i of all emitted arrays.
+func (r ReduceRow) Sums() ([]float64, error) {
+	sums := []float64{}
+	if err := json.Unmarshal(r.Value, &sums); err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
+
+// Stats returns the value of the built-in _stats reducer.
+func (r ReduceRow) Stats() (*Stats, error) {
+	stats := &Stats{}
+	return stats, json.Unmarshal(r.Value, stats)
+}
+
+// ApproxCountDistinct returns the value of the built-in _approx_count_distinct reducer.
+// Requires CouchDB 2.2 or later.
+func (r ReduceRow) ApproxCountDistinct() (int, error) {
+	return r.decodeInt()
+}
+
+// decodeInt decodes the value of the built-in reducers that count rows.
+func (r ReduceRow) decodeInt() (int, error) {
+	var n int
+	if err := json.Unmarshal(r.Value, &n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// reduceParams returns params for reducing at groupLevel.
+// Negative group levels other than GroupExact are rejected.
+func reduceParams(params QueryParameters, groupLevel int) (QueryParameters, error) {
+	if groupLevel < GroupExact {
+		return params, fmt.Errorf("couchdb: group level must be GroupExact, GroupNone or positive but is %d", groupLevel)
+	}
+	reduce := true
+	params.Reduce = &reduce
+	params.Group = nil
+	params.GroupLevel = nil
+	switch {
+	case groupLevel == GroupExact:
+		group := true
+		params.Group = &group
+	case groupLevel > 0:
+		params.GroupLevel = &groupLevel
+	}
+	return params, nil
+}
+
+// Reduce executes the reduce function of the view grouped at groupLevel.
+//
+//	rows, err := db.View("orders").Reduce("totalByDate", 2, QueryParameters{})
+//	for _, row := range rows {
+//		var date []int
+//		row.DecodeKey(&date)
+//		total, err := row.Sum()
+//	}
+func (v *View) Reduce(name string, groupLevel int, params QueryParameters) ([]ReduceRow, error) {
+	rows := []ReduceRow{}
+	if err := v.ReduceRows(name, groupLevel, params, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// ReduceRows works like Reduce but decodes the rows into rows which must be
+// a pointer to a slice of the caller's row type with "key" and "value" fields, e.g.
+//
+//	type row struct {
+//		Key   []string `json:"key"`
+//		Value Stats    `json:"value"`
+//	}
+func (v *View) ReduceRows(name string, groupLevel int, params QueryParameters, rows interface{}) error {
+	params, err := reduceParams(params, groupLevel)
+	if err != nil {
+		return err
+	}
+	_, err = v.GetRows(name, params, rows)
+	return err
+}
@@ -0,0 +1,144 @@
+package couchdb
+
+// Selector is a Mango selector.
+// Selectors are built with the functions below and can be nested.
+//
+//	And(
+//		Eq("type", "user"),
+//		Gt("age", 21),
+//		Or(Regex("name", "^A"), Exists("nickname", true)),
+//	)
+//
+// http://docs.couchdb.org/en/latest/api/database/find.html#selector-syntax
+type Selector map[string]interface{}
+
+// field returns a selector applying a single operator to a field.
+func field(name, operator string, argument interface{}) Selector {
+	return Selector{
+		name: map[string]interface{}{
+			operator: argument,
+		},
+	}
+}
+
+// combine returns a selector applying a combination operator to selectors.
+func combine(operator string, selectors []Selector) Selector {
+	if selectors == nil {
+		selectors = []Selector{}
+	}
+	return Selector{
+		operator: selectors,
+	}
+}
+
+// Eq matches documents whose field is equal to value.
+func Eq(name string, value interface{}) Selector {
+	return field(name, "$eq", value)
+}
+
+// Ne matches documents whose field is not equal to value.
+func Ne(name string, value interface{}) Selector {
+	return field(name, "$ne", value)
+}
+
+// Gt matches documents whose field is greater than value.
+func Gt(name string, value interface{}) Selector {
+	return field(name, "$gt", value)
+}
+
+// Gte matches documents whose field is greater than or equal to value.
+func Gte(name string, value interface{}) Selector {
+	return field(name, "$gte", value)
+}
+
+// Lt matches documents whose field is less than value.
+func Lt(name string, value interface{}) Selector {
+	return field(name, "$lt", value)
+}
+
+// Lte matches documents whose field is less than or equal to value.
+func Lte(name string, value interface{}) Selector {
+	return field(name, "$lte", value)
+}
+
+// In matches documents whose field is equal to one of the values.
+func In(name string, values ...interface{}) Selector {
+	if values == nil {
+		values = []interface{}{}
+	}
+	return field(name, "$in", values)
+}
+
+// Nin matches documents whose field is equal to none of the values.
+func Nin(name string, values ...interface{}) Selector {
+	if values == nil {
+		values = []interface{}{}
+	}
+	return field(name, "$nin", values)
+}
+
+// All matches documents whose array field contains all of the values.
+func All(name string, values ...interface{}) Selector {
+	if values == nil {
+		values = []interface{}{}
+	}
+	return field(name, "$all", values)
+}
+
+// Exists matches documents that have (or do not have) the field.
+func Exists(name string, exists bool) Selector {
+	return field(name, "$exists", exists)
+}
+
+// Type matches documents whose field is of the given type:
+// "null", "boolean", "number", "string", "array" or "object".
+func Type(name string, typ string) Selector {
+	return field(name, "$type", typ)
+}
+
+// Size matches documents whose array field has the given length.
+func Size(name string, size int) Selector {
+	return field(name, "$size", size)
+}
+
+// Mod matches documents whose integer field divided by divisor has the given remainder.
+func Mod(name string, divisor, remainder int) Selector {
+	return field(name, "$mod", []int{divisor, remainder})
+}
+
+// Regex matches documents whose string field matches the regular expression.
+func Regex(name string, pattern string) Selector {
+	return field(name, "$regex", pattern)
+}
+
+// ElemMatch matches documents whose array field contains at least one element matching selector.
+func ElemMatch(name string, selector Selector) Selector {
+	return field(name, "$elemMatch", selector)
+}
+
+// AllMatch matches documents whose array field only contains elements matching selector.
+func AllMatch(name string, selector Selector) Selector {
+	return field(name, "$allMatch", selector)
+}
+
+// And matches documents matching all selectors.
+func And(selectors ...Selector) Selector {
+	return combine("$and", selectors)
+}
+
+// Or matches documents matching at least one of the selectors.
+func Or(selectors ...Selector) Selector {
+	return combine("$or", selectors)
+}
+
+// Nor matches documents matching none of the selectors.
+func Nor(selectors ...Selector) Selector {
+	return combine("$nor", selectors)
+}
+
+// Not matches documents not matching selector.
+func Not(selector Selector) Selector {
+	return Selector{
+		"$not": selector,
+	}
+}
@@ -0,0 +1,17 @@
+package couchdb
+
+import "encoding/json"
+
+// UpdateResponse is the response of an update function.
+// NewRev and ID are only set if the function saved a document.
+type UpdateResponse struct {
+	Body        []byte
+	ContentType string
+	NewRev      string
+	ID          string
+}
+
+// Decode decodes a JSON body into v.
+func (r *UpdateResponse) Decode(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
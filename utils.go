@@ -11,6 +11,7 @@ import (
 	"net/textproto"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/zemirco/uid"
 )
@@ -22,16 +23,18 @@ func mimeType(name string) string {
 }
 
 // Convert HTTP response from CouchDB into Error.
+// Bodies that are not JSON, e.g. from update or show functions, become the Reason.
 func newError(res *http.Response) error {
+	defer res.Body.Close()
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
 		return err
 	}
-	defer res.Body.Close()
 	error := &Error{}
-	err = json.Unmarshal(body, &error)
-	if err != nil {
-		return err
+	if err := json.Unmarshal(body, &error); err != nil {
+		error = &Error{
+			Reason: strings.TrimSpace(string(body)),
+		}
 	}
 	error.Method = res.Request.Method
 	error.URL = res.Request.URL.String()
@@ -2,17 +2,31 @@ package couchdb
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
-
-	"github.com/google/go-querystring/query"
+	"net/url"
+	"strings"
 )
 
 // ViewService is an interface for dealing with a view inside a CouchDB database.
 type ViewService interface {
 	Get(name string, params QueryParameters) (*ViewResponse, error)
-	Post(name string, keys []string, params QueryParameters) (*ViewResponse, error)
+	Post(name string, keys []interface{}, params QueryParameters) (*ViewResponse, error)
+	GetRows(name string, params QueryParameters, rows interface{}) (*ViewResponse, error)
+	PostRows(name string, keys []interface{}, params QueryParameters, rows interface{}) (*ViewResponse, error)
+	Stream(ctx context.Context, name string, params QueryParameters) (*ViewStream, error)
+	Queries(name string, queries []QueryParameters) ([]ViewResponse, error)
+	Info() (*ViewIndexInfo, error)
+	Show(fn, docID string, query url.Values) (*FunctionResponse, error)
+	List(fn, view string, params QueryParameters, query url.Values) (*FunctionResponse, error)
+	Update(fn, docID string, body io.Reader, contentType string) (*UpdateResponse, error)
+	UpdateForm(fn, docID string, form url.Values) (*UpdateResponse, error)
+	Reduce(name string, groupLevel int, params QueryParameters) ([]ReduceRow, error)
+	ReduceRows(name string, groupLevel int, params QueryParameters, rows interface{}) error
 }
 
 // View performs actions and certain view documents
@@ -23,12 +37,7 @@ type View struct {
 
 // Get executes specified view function from specified design document.
 func (v *View) Get(name string, params QueryParameters) (*ViewResponse, error) {
-	q, err := query.Values(params)
-	if err != nil {
-		return nil, err
-	}
-	uri := fmt.Sprintf("%s_view/%s?%s", v.URL, name, q.Encode())
-	res, err := v.Client.Request(http.MethodGet, uri, nil, "")
+	res, err := v.get(name, params)
 	if err != nil {
 		return nil, err
 	}
@@ -40,9 +49,202 @@ func (v *View) Get(name string, params QueryParameters) (*ViewResponse, error) {
 // Post executes specified view function from specified design document.
 // Unlike View.Get for accessing views, View.Post supports
 // the specification of explicit keys to be retrieved from the view results.
-func (v *View) Post(name string, keys []string, params QueryParameters) (*ViewResponse, error) {
+// Keys are sent in the request body so params.Keys is ignored.
+func (v *View) Post(name string, keys []interface{}, params QueryParameters) (*ViewResponse, error) {
+	res, err := v.post(name, keys, params)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var response ViewResponse
+	return &response, json.NewDecoder(res.Body).Decode(&response)
+}
+
+// GetRows works like Get but decodes the rows into rows which must be a pointer
+// to a slice of the caller's row type. The row type declares the fields it needs
+// with the JSON names "id", "key", "value", "doc" and "error", e.g.
+//
+//	type row struct {
+//		Key   []interface{} `json:"key"`
+//		Value int           `json:"value"`
+//		Doc   *Person       `json:"doc"`
+//		Error string        `json:"error"`
+//	}
+//
+// RawRow keeps the raw JSON of every row. The returned response has no Rows.
+func (v *View) GetRows(name string, params QueryParameters, rows interface{}) (*ViewResponse, error) {
+	res, err := v.get(name, params)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	return decodeRows(res, rows)
+}
+
+// PostRows works like Post but decodes the rows into rows like GetRows.
+// Keys that do not exist in the view are omitted from the rows.
+func (v *View) PostRows(name string, keys []interface{}, params QueryParameters, rows interface{}) (*ViewResponse, error) {
+	res, err := v.post(name, keys, params)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	return decodeRows(res, rows)
+}
+
+// Queries executes multiple queries against the view in a single request.
+// It returns one result per query in the same order. Requires CouchDB 2.2 or later.
+//
+// http://docs.couchdb.org/en/latest/api/ddoc/views.html#sending-multiple-queries-to-a-view
+func (v *View) Queries(name string, queries []QueryParameters) ([]ViewResponse, error) {
+	return multiQuery(v.Client, fmt.Sprintf("%s_view/%s/queries", v.URL, name), queries)
+}
+
+// Info returns info about the view index of the design document.
+//
+// http://docs.couchdb.org/en/latest/api/ddoc/common.html#get--db-_design-ddoc-_info
+func (v *View) Info() (*ViewIndexInfo, error) {
+	res, err := v.Client.Request(http.MethodGet, v.URL+"_info", nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	response := &ViewIndexInfo{}
+	return response, json.NewDecoder(res.Body).Decode(response)
+}
+
+// Show executes the show function fn of the design document for the document docID.
+// docID may be empty to call the function without a document.
+// query is passed to the function as req.query.
+// Show functions are deprecated since CouchDB 3.0.
+//
+// http://docs.couchdb.org/en/latest/api/ddoc/render.html#db-design-design-doc-show-show-name
+func (v *View) Show(fn, docID string, query url.Values) (*FunctionResponse, error) {
+	uri := fmt.Sprintf("%s_show/%s", v.URL, url.PathEscape(fn))
+	if docID != "" {
+		uri += "/" + docPath(docID)
+	}
+	if len(query) > 0 {
+		uri += "?" + query.Encode()
+	}
+	res, err := v.Client.Request(http.MethodGet, uri, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return newFunctionResponse(res), nil
+}
+
+// List executes the list function fn of the design document on the results of view.
+// view is either the name of a view in the same design document
+// or "ddoc/view" for a view in another design document.
+// query holds additional parameters for the list function and is merged with params.
+// List functions are deprecated since CouchDB 3.0.
+//
+// http://docs.couchdb.org/en/latest/api/ddoc/render.html#db-design-design-doc-list-list-name-view-name
+func (v *View) List(fn, view string, params QueryParameters, query url.Values) (*FunctionResponse, error) {
+	q, err := params.values()
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range query {
+		for _, value := range values {
+			q.Add(name, value)
+		}
+	}
+	// escape the optional design document and the view separately
+	parts := strings.SplitN(view, "/", 2)
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	uri := fmt.Sprintf("%s_list/%s/%s?%s", v.URL, url.PathEscape(fn), strings.Join(parts, "/"), q.Encode())
+	res, err := v.Client.Request(http.MethodGet, uri, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return newFunctionResponse(res), nil
+}
+
+// Update executes the update function fn of the design document for the document docID.
+// docID may be empty to let the function create a new document.
+// Errors thrown by the function are returned as *Error.
+//
+// http://docs.couchdb.org/en/latest/api/ddoc/render.html#db-design-design-doc-update-update-name
+func (v *View) Update(fn, docID string, body io.Reader, contentType string) (*UpdateResponse, error) {
+	method := http.MethodPost
+	uri := fmt.Sprintf("%s_update/%s", v.URL, url.PathEscape(fn))
+	if docID != "" {
+		method = http.MethodPut
+		uri += "/" + docPath(docID)
+	}
+	res, err := v.Client.Request(method, uri, body, contentType)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &UpdateResponse{
+		Body:        b,
+		ContentType: res.Header.Get("Content-Type"),
+		NewRev:      res.Header.Get("X-Couch-Update-NewRev"),
+		ID:          res.Header.Get("X-Couch-Id"),
+	}, nil
+}
+
+// UpdateForm executes the update function like Update with form values
+// which the function reads from req.form.
+func (v *View) UpdateForm(fn, docID string, form url.Values) (*UpdateResponse, error) {
+	return v.Update(fn, docID, strings.NewReader(form.Encode()), "application/x-www-form-urlencoded")
+}
+
+// multiQuery posts queries to the queries endpoint at uri.
+func multiQuery(client *Client, uri string, queries []QueryParameters) ([]ViewResponse, error) {
+	if queries == nil {
+		queries = []QueryParameters{}
+	}
+	for i := range queries {
+		if err := queries[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+	content := struct {
+		Queries []QueryParameters `json:"queries"`
+	}{
+		Queries: queries,
+	}
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(content); err != nil {
+		return nil, err
+	}
+	res, err := client.Request(http.MethodPost, uri, &b, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var response struct {
+		Results []ViewResponse `json:"results"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	return response.Results, nil
+}
+
+func (v *View) get(name string, params QueryParameters) (*http.Response, error) {
+	q, err := params.values()
+	if err != nil {
+		return nil, err
+	}
+	uri := fmt.Sprintf("%s_view/%s?%s", v.URL, name, q.Encode())
+	return v.Client.Request(http.MethodGet, uri, nil, "")
+}
+
+func (v *View) post(name string, keys []interface{}, params QueryParameters) (*http.Response, error) {
+	params.Keys = nil
 	content := struct {
-		Keys []string `json:"keys"`
+		Keys []interface{} `json:"keys"`
 	}{
 		Keys: keys,
 	}
@@ -52,16 +254,25 @@ func (v *View) Post(name string, keys []string, params QueryParameters) (*ViewRe
 		return nil, err
 	}
 	// create query string
-	q, err := query.Values(params)
+	q, err := params.values()
 	if err != nil {
 		return nil, err
 	}
 	url := fmt.Sprintf("%s_view/%s?%s", v.URL, name, q.Encode())
-	res, err := v.Client.Request(http.MethodPost, url, &b, "application/json")
-	if err != nil {
+	return v.Client.Request(http.MethodPost, url, &b, "application/json")
+}
+
+// decodeRows decodes the view response in res and its rows into rows.
+func decodeRows(res *http.Response, rows interface{}) (*ViewResponse, error) {
+	var response struct {
+		ViewResponse
+		Rows json.RawMessage `json:"rows"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
 		return nil, err
 	}
-	defer res.Body.Close()
-	var response ViewResponse
-	return &response, json.NewDecoder(res.Body).Decode(&response)
+	if len(response.Rows) == 0 {
+		return &response.ViewResponse, nil
+	}
+	return &response.ViewResponse, json.Unmarshal(response.Rows, rows)
 }
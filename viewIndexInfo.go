@@ -0,0 +1,31 @@
+package couchdb
+
+// ViewIndexInfo has info about the view index of a design document.
+// http://docs.couchdb.org/en/latest/api/ddoc/common.html#get--db-_design-ddoc-_info
+type ViewIndexInfo struct {
+	Name      string    `json:"name"`
+	ViewIndex ViewIndex `json:"view_index"`
+}
+
+// ViewIndex is the state of a view index.
+// CouchDB 1.x reports DiskSize and DataSize instead of Sizes.
+type ViewIndex struct {
+	CompactRunning bool           `json:"compact_running"`
+	Language       string         `json:"language"`
+	PurgeSeq       int            `json:"purge_seq"`
+	Signature      string         `json:"signature"`
+	Sizes          ViewIndexSizes `json:"sizes"`
+	UpdateSeq      Seq            `json:"update_seq"`
+	UpdaterRunning bool           `json:"updater_running"`
+	WaitingClients int            `json:"waiting_clients"`
+	WaitingCommit  bool           `json:"waiting_commit"`
+	DiskSize       int            `json:"disk_size,omitempty"`
+	DataSize       int            `json:"data_size,omitempty"`
+}
+
+// ViewIndexSizes are the sizes of a view index in bytes.
+type ViewIndexSizes struct {
+	Active   int `json:"active"`
+	External int `json:"external"`
+	File     int `json:"file"`
+}
@@ -0,0 +1,184 @@
+package couchdb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidPageToken is returned by ViewPager.Page for malformed tokens.
+var ErrInvalidPageToken = errors.New("couchdb: invalid page token")
+
+// ViewPager pages through a view with startkey and startkey_docid
+// instead of skip. Every page has a token to the next and the previous page
+// so rows with duplicate keys are neither skipped nor repeated.
+// The view must not be reduced, so set Reduce to false for views with a reduce function.
+//
+//	pager := NewViewPager(db.View("person"), "byAge", QueryParameters{}, 20)
+//	page, err := pager.Page("")
+//	// later
+//	page, err = pager.Page(page.Next)
+type ViewPager struct {
+	view     ViewService
+	name     string
+	params   QueryParameters
+	pageSize int
+	err      error
+}
+
+// ViewPage is a single page of rows.
+// Next and Prev are empty on the last and first page.
+type ViewPage struct {
+	Rows []Row
+	Next string
+	Prev string
+}
+
+// pageToken is the decoded form of ViewPage.Next and ViewPage.Prev.
+// A forward token points to the first row of the page,
+// a backward token to the row right after the page.
+type pageToken struct {
+	Key      json.RawMessage `json:"k"`
+	DocID    string          `json:"id"`
+	Backward bool            `json:"b,omitempty"`
+}
+
+// NewViewPager returns a pager over the view with the given name.
+// Limit and Skip of params are ignored. Key, StartKey, EndKey and Descending
+// restrict and order the rows as usual. Keys cannot be paged and make
+// Page return ErrPagingKeys.
+func NewViewPager(view ViewService, name string, params QueryParameters, pageSize int) *ViewPager {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	params.Limit = nil
+	params.Skip = nil
+	params, err := pagingParams(params)
+	return &ViewPager{
+		view:     view,
+		name:     name,
+		params:   params,
+		pageSize: pageSize,
+		err:      err,
+	}
+}
+
+// Page returns the page for token. An empty token returns the first page.
+func (p *ViewPager) Page(token string) (*ViewPage, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	if token == "" {
+		return p.forward(nil)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+	t := &pageToken{}
+	if err := json.Unmarshal(b, t); err != nil || t.DocID == "" {
+		return nil, ErrInvalidPageToken
+	}
+	if t.Backward {
+		return p.backward(t)
+	}
+	return p.forward(t)
+}
+
+// forward returns the page starting at t. It asks for one extra row
+// whose key and id are the start of the following page.
+func (p *ViewPager) forward(t *pageToken) (*ViewPage, error) {
+	params := p.params
+	if t != nil {
+		params.StartKey = t.Key
+		params.StartKeyDocID = &t.DocID
+	}
+	limit := p.pageSize + 1
+	params.Limit = &limit
+	res, err := p.view.Get(p.name, params)
+	if err != nil {
+		return nil, err
+	}
+	page := &ViewPage{
+		Rows: res.Rows,
+	}
+	if len(res.Rows) > p.pageSize {
+		page.Rows = res.Rows[:p.pageSize]
+		if page.Next, err = encodePageToken(res.Rows[p.pageSize], false); err != nil {
+			return nil, err
+		}
+	}
+	if t != nil && len(page.Rows) > 0 {
+		if page.Prev, err = encodePageToken(page.Rows[0], true); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// backward returns the page ending right before t by reading the view
+// in the opposite direction starting at t.
+func (p *ViewPager) backward(t *pageToken) (*ViewPage, error) {
+	params := p.params
+	descending := p.params.Descending == nil || !*p.params.Descending
+	inclusiveEnd := true
+	params.Descending = &descending
+	params.StartKey = t.Key
+	params.StartKeyDocID = &t.DocID
+	// the start of the original range is the end in the opposite direction
+	params.EndKey = p.params.StartKey
+	params.EndKeyDocID = p.params.StartKeyDocID
+	params.InclusiveEnd = &inclusiveEnd
+	// the row at t belongs to the next page, one more row tells if there is a previous page
+	limit := p.pageSize + 2
+	params.Limit = &limit
+	res, err := p.view.Get(p.name, params)
+	if err != nil {
+		return nil, err
+	}
+	rows := res.Rows
+	if len(rows) > 0 && rows[0].ID == t.DocID {
+		key, err := json.Marshal(rows[0].Key)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(key, t.Key) {
+			rows = rows[1:]
+		}
+	}
+	// reached the beginning, so return a full first page instead
+	if len(rows) <= p.pageSize {
+		return p.forward(nil)
+	}
+	rows = rows[:p.pageSize]
+	page := &ViewPage{
+		Rows: make([]Row, len(rows)),
+	}
+	for i, row := range rows {
+		page.Rows[len(rows)-1-i] = row
+	}
+	if page.Next, err = encodePageToken(Row{ID: t.DocID, Key: t.Key}, false); err != nil {
+		return nil, err
+	}
+	if page.Prev, err = encodePageToken(page.Rows[0], true); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+func encodePageToken(row Row, backward bool) (string, error) {
+	key, err := json.Marshal(row.Key)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(pageToken{
+		Key:      key,
+		DocID:    row.ID,
+		Backward: backward,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
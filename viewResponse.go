@@ -1,17 +1,32 @@
 package couchdb
 
+import "encoding/json"
+
 // ViewResponse is response for querying design documents.
 type ViewResponse struct {
 	Offset    int   `json:"offset,omitempty"`
 	Rows      []Row `json:"rows,omitempty"`
 	TotalRows int   `json:"total_rows,omitempty"`
-	UpdateSeq int   `json:"update_seq,omitempty"`
+	UpdateSeq Seq   `json:"update_seq,omitempty"`
 }
 
 // Row is single row inside design document query response.
+// Error is set for rows that could not be found when querying explicit keys,
+// e.g. "not_found".
 type Row struct {
 	ID    string                 `json:"id"`
 	Key   interface{}            `json:"key"`
 	Value interface{}            `json:"value,omitempty"`
 	Doc   map[string]interface{} `json:"doc,omitempty"`
+	Error string                 `json:"error,omitempty"`
+}
+
+// RawRow is a row whose key, value and document are kept as raw JSON.
+// Use it with View.GetRows and View.PostRows to decode parts of a row later.
+type RawRow struct {
+	ID    string          `json:"id"`
+	Key   json.RawMessage `json:"key"`
+	Value json.RawMessage `json:"value,omitempty"`
+	Doc   json.RawMessage `json:"doc,omitempty"`
+	Error string          `json:"error,omitempty"`
 }
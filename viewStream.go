@@ -0,0 +1,171 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ViewStream reads the rows of a view response one at a time.
+// The response is tokenized while it is read so memory use does not
+// depend on the number of rows. TotalRows, Offset and UpdateSeq are
+// set as soon as they were read, which is usually before the first row.
+//
+//	stream, err := db.View("person").Stream(ctx, "byAge", QueryParameters{})
+//	if err != nil {
+//		// handle error
+//	}
+//	defer stream.Close()
+//	for stream.Next() {
+//		row, err := stream.Row()
+//		if err != nil {
+//			// handle error
+//		}
+//	}
+//	if err := stream.Err(); err != nil {
+//		// handle error
+//	}
+type ViewStream struct {
+	TotalRows int
+	Offset    int
+	UpdateSeq Seq
+
+	body   io.ReadCloser
+	dec    *json.Decoder
+	inRows bool
+	raw    json.RawMessage
+	done   bool
+	err    error
+}
+
+// Stream executes specified view function from specified design document
+// and returns a stream over the resulting rows.
+// The caller must close the stream.
+func (v *View) Stream(ctx context.Context, name string, params QueryParameters) (*ViewStream, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	q, err := params.values()
+	if err != nil {
+		return nil, err
+	}
+	uri := fmt.Sprintf("%s_view/%s?%s", v.URL, name, q.Encode())
+	res, err := v.Client.RequestContext(ctx, http.MethodGet, uri, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return newViewStream(res.Body), nil
+}
+
+func newViewStream(body io.ReadCloser) *ViewStream {
+	s := &ViewStream{
+		body: body,
+		dec:  json.NewDecoder(body),
+	}
+	if err := s.expectDelim('{'); err != nil {
+		s.err = err
+	}
+	return s
+}
+
+// Next advances the stream to the next row. It returns false when there are
+// no more rows or an error occurred.
+func (s *ViewStream) Next() bool {
+	if s.err != nil || s.done {
+		return false
+	}
+	for {
+		if s.inRows {
+			if s.dec.More() {
+				s.raw = nil
+				if err := s.dec.Decode(&s.raw); err != nil {
+					s.err = err
+					return false
+				}
+				return true
+			}
+			if err := s.expectDelim(']'); err != nil {
+				s.err = err
+				return false
+			}
+			s.inRows = false
+			continue
+		}
+		if !s.dec.More() {
+			s.done = true
+			s.err = s.expectDelim('}')
+			return false
+		}
+		if err := s.readField(); err != nil {
+			s.err = err
+			return false
+		}
+	}
+}
+
+// readField reads a single top level field of the response.
+func (s *ViewStream) readField() error {
+	t, err := s.dec.Token()
+	if err != nil {
+		return err
+	}
+	switch t {
+	case "total_rows":
+		return s.dec.Decode(&s.TotalRows)
+	case "offset":
+		return s.dec.Decode(&s.Offset)
+	case "update_seq":
+		return s.dec.Decode(&s.UpdateSeq)
+	case "rows":
+		if err := s.expectDelim('['); err != nil {
+			return err
+		}
+		s.inRows = true
+		return nil
+	}
+	// skip unknown fields
+	var skip json.RawMessage
+	return s.dec.Decode(&skip)
+}
+
+func (s *ViewStream) expectDelim(delim json.Delim) error {
+	t, err := s.dec.Token()
+	if err != nil {
+		return err
+	}
+	if t != delim {
+		return fmt.Errorf("couchdb: expected %v in view response but got %v", delim, t)
+	}
+	return nil
+}
+
+// Row returns the current row. It returns an error if the row does not fit Row,
+// e.g. a non string id, use Decode for such rows.
+func (s *ViewStream) Row() (Row, error) {
+	var row Row
+	if err := s.Decode(&row); err != nil {
+		return Row{}, err
+	}
+	return row, nil
+}
+
+// Decode decodes the current row into v like View.GetRows does for all rows.
+func (s *ViewStream) Decode(v interface{}) error {
+	if s.raw == nil {
+		return errors.New("couchdb: Decode called without a current row")
+	}
+	return json.Unmarshal(s.raw, v)
+}
+
+// Err returns the first error that occurred while reading the stream.
+func (s *ViewStream) Err() error {
+	return s.err
+}
+
+// Close closes the underlying response body.
+func (s *ViewStream) Close() error {
+	return s.body.Close()
+}